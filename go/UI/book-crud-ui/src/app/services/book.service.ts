@@ -1,7 +1,14 @@
 import { Injectable } from '@angular/core';
 import { HttpClient } from '@angular/common/http';
 import { Book } from '../models/book';
-import { Observable } from 'rxjs';
+import { EMPTY, Observable, expand, reduce } from 'rxjs';
+
+// One page of the GET /books response
+interface BookPage {
+    data: Book[];
+    total: number;
+    links: { next?: string };
+}
 
 @Injectable({
     providedIn: 'root'
@@ -12,8 +19,15 @@ export class BookService {
     constructor(private http: HttpClient) {
     }
 
+    // getBooks follows the pages of GET /books until the last one, with
+    // cursors so that books added or deleted meanwhile do not shift them
     getBooks(): Observable<Book[]> {
-        return this.http.get<Book[]>(this.apiUrl);
+        return this.http.get<BookPage>(this.apiUrl, { params: { limit: 100, cursor: '' } }).pipe(
+            expand(page => page.links.next
+                ? this.http.get<BookPage>(new URL(page.links.next, this.apiUrl).toString())
+                : EMPTY),
+            reduce((books: Book[], page) => books.concat(page.data), [])
+        );
     }
     createBook(book: Book): Observable<Book> {
         return this.http.post<Book>(this.apiUrl, book);
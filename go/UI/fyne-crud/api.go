@@ -6,6 +6,8 @@ import (
 	"fmt"
 
 	"net/http"
+	"net/url"
+	"strconv"
 )
 
 const baseURL = "http://localhost:8080/books"
@@ -17,22 +19,78 @@ type Book struct {
 	Year   int    `json:"year"`
 }
 
-func fetchBooks() ([]Book, error) {
-	resp, err := http.Get(baseURL)
+// BookPage is one page of the GET /books response.
+type BookPage struct {
+	Data       []Book `json:"data"`
+	Total      int    `json:"total"`
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor"`
+}
+
+const pageLimit = 20
+
+// fetchBooks returns the page of books following cursor, or the first page
+// when cursor is empty.
+func fetchBooks(cursor string) (BookPage, error) {
+	query := url.Values{}
+	query.Set("cursor", cursor)
+	query.Set("limit", strconv.Itoa(pageLimit))
+	query.Set("sort", "title")
+
+	resp, err := http.Get(baseURL + "?" + query.Encode())
 	if err != nil {
-		return nil, err
+		return BookPage{}, err
 	}
 	defer resp.Body.Close()
 
-	var books []Book
-	err = json.NewDecoder(resp.Body).Decode(&books)
-	return books, err
+	var bookPage BookPage
+	err = json.NewDecoder(resp.Body).Decode(&bookPage)
+	return bookPage, err
+}
+
+// FieldError is one failed validation rule reported by the server.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// APIError is an RFC 7807 problem returned by the server. Fields lists the
+// invalid inputs when the server rejected a book.
+type APIError struct {
+	Title  string       `json:"title"`
+	Status int          `json:"status"`
+	Detail string       `json:"detail"`
+	Fields []FieldError `json:"errors"`
+}
+
+func (e *APIError) Error() string {
+	if e.Detail == "" {
+		return e.Title
+	}
+	return e.Title + ": " + e.Detail
+}
+
+// checkResponse turns an error status into an *APIError.
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode < 400 {
+		return nil
+	}
+	apiErr := &APIError{}
+	if err := json.NewDecoder(resp.Body).Decode(apiErr); err != nil || apiErr.Title == "" {
+		apiErr.Title = resp.Status
+	}
+	return apiErr
 }
 
 func createBook(book Book) error {
 	data, _ := json.Marshal(book)
-	_, err := http.Post(baseURL, "application/json", bytes.NewBuffer(data))
-	return err
+	resp, err := http.Post(baseURL, "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkResponse(resp)
 }
 
 func updateBook(book Book) error {
@@ -43,8 +101,12 @@ func updateBook(book Book) error {
 	}
 	req.Header.Set("Content-Type", "application/json")
 	client := &http.Client{}
-	_, err = client.Do(req)
-	return err
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkResponse(resp)
 }
 
 func deleteBook(id string) error {
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 
 	"fyne.io/fyne/v2"
@@ -10,14 +11,51 @@ import (
 )
 
 func bookUI(win fyne.Window) fyne.CanvasObject {
-	var books []Book
-	var err error
-	books, err = fetchBooks()
+	bookPage, err := fetchBooks("")
 	if err != nil {
 		dialog.ShowError(err, win)
 	}
+	books := bookPage.Data
+	nextCursor := bookPage.NextCursor
+	loading := false
 
-	bookList := widget.NewList(
+	countLabel := widget.NewLabel("")
+	updateCount := func() {
+		countLabel.SetText(fmt.Sprintf("Showing %d of %d books", len(books), bookPage.Total))
+	}
+	updateCount()
+
+	var bookList *widget.List
+
+	// loadMore appends the next page once the list scrolls to its last row
+	loadMore := func() {
+		if loading || nextCursor == "" {
+			return
+		}
+		loading = true
+		cursor := nextCursor
+		go func() {
+			next, err := fetchBooks(cursor)
+			fyne.Do(func() {
+				loading = false
+				if err != nil {
+					dialog.ShowError(err, win)
+					return
+				}
+				// Drop pages that arrive after a refresh restarted the list
+				if cursor != nextCursor {
+					return
+				}
+				bookPage = next
+				books = append(books, next.Data...)
+				nextCursor = next.NextCursor
+				bookList.Refresh()
+				updateCount()
+			})
+		}()
+	}
+
+	bookList = widget.NewList(
 		func() int { return len(books) },
 		func() fyne.CanvasObject {
 			return container.NewPadded(container.NewHBox(
@@ -31,16 +69,24 @@ func bookUI(win fyne.Window) fyne.CanvasObject {
 			author := box.Objects[1].(*widget.Label)
 			title.SetText(books[i].Title)
 			author.SetText("  by " + books[i].Author)
+			if i == len(books)-1 {
+				loadMore()
+			}
 		},
 	)
 
 	refresh := func() {
-		books, err = fetchBooks()
+		bookPage, err = fetchBooks("")
 		if err != nil {
 			dialog.ShowError(err, win)
 			return
 		}
+		books = bookPage.Data
+		nextCursor = bookPage.NextCursor
+		bookList.UnselectAll()
 		bookList.Refresh()
+		bookList.ScrollToTop()
+		updateCount()
 	}
 
 	bookList.OnSelected = func(i widget.ListItemID) {
@@ -52,7 +98,7 @@ func bookUI(win fyne.Window) fyne.CanvasObject {
 		editForm(Book{}, win, refresh)
 	})
 
-	content := container.NewBorder(nil, addBtn, nil, nil, bookList)
+	content := container.NewBorder(nil, container.NewVBox(countLabel, addBtn), nil, nil, bookList)
 	sized := container.NewPadded(content)
 	sized.Resize(fyne.NewSize(700, 600))
 	return sized
@@ -89,12 +135,25 @@ func editForm(book Book, win fyne.Window, refresh func()) {
 		year.SetText(fmt.Sprintf("%d", book.Year))
 	}
 
-	form := &widget.Form{
-		Items: []*widget.FormItem{
-			{Text: "Title", Widget: container.NewPadded(title)},
-			{Text: "Author", Widget: container.NewPadded(author)},
-			{Text: "Year", Widget: container.NewPadded(year)},
-		},
+	// Each item starts with a hint so the form has room to show a server
+	// validation message in its place
+	hints := map[string]string{
+		"title":  "Required, up to 200 characters",
+		"author": "Required, up to 100 characters",
+		"year":   "Between 0 and the current year",
+	}
+	titleItem := &widget.FormItem{Text: "Title", Widget: container.NewPadded(title), HintText: hints["title"]}
+	authorItem := &widget.FormItem{Text: "Author", Widget: container.NewPadded(author), HintText: hints["author"]}
+	yearItem := &widget.FormItem{Text: "Year", Widget: container.NewPadded(year), HintText: hints["year"]}
+	fieldItems := map[string]*widget.FormItem{
+		"title":  titleItem,
+		"author": authorItem,
+		"year":   yearItem,
+	}
+
+	var form *widget.Form
+	form = &widget.Form{
+		Items: []*widget.FormItem{titleItem, authorItem, yearItem},
 		OnSubmit: func() {
 			book.Title = title.Text
 			book.Author = author.Text
@@ -107,6 +166,22 @@ func editForm(book Book, win fyne.Window, refresh func()) {
 				err = updateBook(book)
 			}
 
+			// Show the server's validation messages under each input
+			for field, item := range fieldItems {
+				item.HintText = hints[field]
+			}
+			var apiErr *APIError
+			if errors.As(err, &apiErr) && len(apiErr.Fields) > 0 {
+				for _, fe := range apiErr.Fields {
+					if item, ok := fieldItems[fe.Field]; ok {
+						item.HintText = fe.Message
+					}
+				}
+				form.Refresh()
+				return
+			}
+			form.Refresh()
+
 			if err != nil {
 				dialog.ShowError(err, win)
 			} else {
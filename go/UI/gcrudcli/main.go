@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -16,31 +17,109 @@ import (
 )
 
 type Book struct {
-	ID     interface{} `json:"id,omitempty"`
-	Title  string      `json:"title"`
-	Author string      `json:"author"`
-	Year   int         `json:"year"`
+	ID        interface{} `json:"id,omitempty"`
+	Title     string      `json:"title"`
+	Author    string      `json:"author"`
+	Year      int         `json:"year"`
+	DeletedAt time.Time   `json:"deletedAt,omitzero"`
 }
 
+// BookPage is one page of the GET /books response.
+type BookPage struct {
+	Data       []Book `json:"data"`
+	Total      int64  `json:"total"`
+	Page       int64  `json:"page"`
+	Limit      int64  `json:"limit"`
+	NextCursor string `json:"next_cursor"`
+	Links      struct {
+		Next string `json:"next"`
+		Prev string `json:"prev"`
+	} `json:"links"`
+}
+
+// HistoryEntry is one recorded change from GET /books/:id/history.
+type HistoryEntry struct {
+	Action    string        `json:"action"`
+	Actor     string        `json:"actor"`
+	Timestamp time.Time     `json:"timestamp"`
+	Version   int64         `json:"version"`
+	Changes   []FieldChange `json:"changes"`
+}
+
+// FieldChange is one field's value before and after a recorded change.
+type FieldChange struct {
+	Field  string `json:"field"`
+	Before any    `json:"before"`
+	After  any    `json:"after"`
+}
+
+// BulkResult is the outcome of one operation of POST /books/_bulk.
+type BulkResult struct {
+	Status int      `json:"status"`
+	ID     string   `json:"id"`
+	Error  *Problem `json:"error"`
+}
+
+// BulkResponse is the response of POST /books/_bulk.
+type BulkResponse struct {
+	Results    []BulkResult `json:"results"`
+	Failed     int          `json:"failed"`
+	RolledBack bool         `json:"rolledBack"`
+}
+
+// bulkBatchSize is the most operations the server takes in one bulk request.
+const bulkBatchSize = 1000
+
 const baseURL = "http://localhost:8080"
 
+// actorName is sent as X-Actor on writes so the server's history records
+// who made them.
+func actorName() string {
+	if user := os.Getenv("USER"); user != "" {
+		return user
+	}
+	return "gcrudcli"
+}
+
 // Color outputs
 var successColor = color.New(color.FgGreen).SprintFunc()
 var errorColor = color.New(color.FgRed).SprintFunc()
 var infoColor = color.New(color.FgCyan).SprintFunc()
 var headerColor = color.New(color.FgYellow).SprintFunc()
 
+// FieldError is one failed validation rule reported by the server.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Problem is an RFC 7807 error response from the server.
+type Problem struct {
+	Type   string       `json:"type"`
+	Title  string       `json:"title"`
+	Status int          `json:"status"`
+	Detail string       `json:"detail"`
+	Errors []FieldError `json:"errors"`
+}
+
 func checkResponse(resp *http.Response) error {
 	if resp.StatusCode >= 400 {
-		var errResp struct {
-			Error string `json:"error"`
-		}
-		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
+		var problem Problem
+		if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil || problem.Title == "" {
 			// If we can't decode the error response, return the status
 			return fmt.Errorf("%s", resp.Status)
 		}
-		// Return the server's error message
-		return fmt.Errorf("%s", errResp.Error)
+
+		msg := problem.Title
+		if problem.Detail != "" {
+			msg += ": " + problem.Detail
+		}
+		// List each invalid field under the server's error message
+		for _, fe := range problem.Errors {
+			msg += fmt.Sprintf("\n   • %s: %s", fe.Field, fe.Message)
+		}
+		return fmt.Errorf("%s", msg)
 	}
 	return nil
 }
@@ -73,38 +152,40 @@ func getIntInput(label string, defaultValue int) (int, error) {
 	return num, nil
 }
 
-func getValidBookID(prompt string) (string, error) {
+// getValidBookID prompts for a book ID and checks that the book exists. It
+// also returns the book's ETag so that a later write can send it in If-Match.
+func getValidBookID(prompt string) (string, string, error) {
 	id, err := getStringInput(prompt, "")
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
 	id = strings.TrimSpace(id)
 	if id == "" {
 		fmt.Println(errorColor("Error: Book ID cannot be empty"))
-		return "", fmt.Errorf("empty ID")
+		return "", "", fmt.Errorf("empty ID")
 	}
 
 	// Check if book exists
 	resp, err := http.Get(fmt.Sprintf("%s/books/%s", baseURL, id))
 	if err != nil {
 		fmt.Println(errorColor("❌ Error: Could not verify book ID. Server may be down."))
-		return "", fmt.Errorf("server down")
+		return "", "", fmt.Errorf("server down")
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotFound {
 		fmt.Println(errorColor("❌ Error: Book not found."))
 		fmt.Println(infoColor("💡 Hint: Use the 'fetch' command to see available book IDs."))
-		return "", fmt.Errorf("not found")
+		return "", "", fmt.Errorf("not found")
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		fmt.Println(errorColor("❌ Error: Invalid book ID or server error"))
-		return "", fmt.Errorf("server error")
+		return "", "", fmt.Errorf("server error")
 	}
 
-	return id, nil
+	return id, resp.Header.Get("ETag"), nil
 }
 
 var rootCmd = &cobra.Command{
@@ -117,10 +198,44 @@ This application allows you to create, read, update, and delete books from the l
 var fetchCmd = &cobra.Command{
 	Use:   "fetch",
 	Short: infoColor("Fetch all books"),
-	Long:  infoColor(`Fetch retrieves all books from the database and displays them.`),
-	Args:  cobra.NoArgs,
+	Long: infoColor(`Fetch retrieves books from the database one page at a time and displays them.
+Example: gcrudcli fetch --page 2 --limit 10 --sort title,-year --author "Author Name" --year-gte 2000
+Use --cursor "" to start cursor paging and pass the printed cursor to get the next page.`),
+	Args: cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		resp, err := http.Get(baseURL + "/books")
+		page, _ := cmd.Flags().GetInt("page")
+		limit, _ := cmd.Flags().GetInt("limit")
+		sort, _ := cmd.Flags().GetString("sort")
+		title, _ := cmd.Flags().GetString("title")
+		author, _ := cmd.Flags().GetString("author")
+		yearGte, _ := cmd.Flags().GetInt("year-gte")
+		yearLte, _ := cmd.Flags().GetInt("year-lte")
+		cursor, _ := cmd.Flags().GetString("cursor")
+
+		query := url.Values{}
+		if cmd.Flags().Changed("cursor") {
+			query.Set("cursor", cursor)
+		} else {
+			query.Set("page", strconv.Itoa(page))
+		}
+		query.Set("limit", strconv.Itoa(limit))
+		if sort != "" {
+			query.Set("sort", sort)
+		}
+		if title != "" {
+			query.Set("title", title)
+		}
+		if author != "" {
+			query.Set("author", author)
+		}
+		if yearGte != 0 {
+			query.Set("year_gte", strconv.Itoa(yearGte))
+		}
+		if yearLte != 0 {
+			query.Set("year_lte", strconv.Itoa(yearLte))
+		}
+
+		resp, err := http.Get(baseURL + "/books?" + query.Encode())
 		if err != nil {
 			fmt.Println(errorColor("❌ Error fetching books:", err))
 			return
@@ -132,24 +247,113 @@ var fetchCmd = &cobra.Command{
 			return
 		}
 
-		var books []Book
-		if err := json.NewDecoder(resp.Body).Decode(&books); err != nil {
+		var bookPage BookPage
+		if err := json.NewDecoder(resp.Body).Decode(&bookPage); err != nil {
 			fmt.Println(errorColor("❌ Error decoding response:", err))
 			return
 		}
 
-		if len(books) == 0 {
+		if len(bookPage.Data) == 0 {
 			fmt.Println(infoColor("📚 No books found"))
 			return
 		}
 
-		for _, book := range books {
+		for _, book := range bookPage.Data {
+			fmt.Printf("%s\n", headerColor("📖 Book Details:"))
+			fmt.Printf("🔑 ID: %s\n", infoColor(book.ID))
+			fmt.Printf("📕 Title: %s\n", infoColor(book.Title))
+			fmt.Printf("✍️  Author: %s\n", infoColor(book.Author))
+			fmt.Printf("📅 Year: %d\n\n", book.Year)
+		}
+
+		if cmd.Flags().Changed("cursor") {
+			fmt.Printf("📄 Showing %d of %d books\n", len(bookPage.Data), bookPage.Total)
+			if bookPage.NextCursor != "" {
+				fmt.Println(infoColor(fmt.Sprintf("💡 Hint: Use \"%s\" to see the next page.", nextPageCommand(cmd, "cursor", bookPage.NextCursor))))
+			}
+			return
+		}
+
+		fmt.Printf("📄 Page %d, showing %d of %d books\n", bookPage.Page, len(bookPage.Data), bookPage.Total)
+		if bookPage.Links.Next != "" {
+			fmt.Println(infoColor(fmt.Sprintf("💡 Hint: Use \"%s\" to see the next page.", nextPageCommand(cmd, "page", strconv.FormatInt(bookPage.Page+1, 10)))))
+		}
+	},
+}
+
+// listFlags are the flags of listing commands that pick and order the
+// books, which the next page must repeat.
+var listFlags = []string{"limit", "sort", "title", "author", "year-gte", "year-lte"}
+
+// nextPageCommand returns the command for the next page of cmd: the list
+// flags the user gave and the paging flag set to value.
+func nextPageCommand(cmd *cobra.Command, pagingFlag, value string) string {
+	parts := []string{cmd.Name()}
+	for _, name := range listFlags {
+		if f := cmd.Flags().Lookup(name); f != nil && f.Changed {
+			parts = append(parts, "--"+name+"="+shellQuote(f.Value.String()))
+		}
+	}
+	parts = append(parts, "--"+pagingFlag+"="+shellQuote(value))
+	return strings.Join(parts, " ")
+}
+
+// shellQuote quotes s for a shell when it is empty or has characters a
+// shell would split or expand.
+func shellQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t'\"$`\\*?&;|<>()!#~") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+var getCmd = &cobra.Command{
+	Use:   "get",
+	Short: infoColor("Get books by ID"),
+	Long: infoColor(`Get retrieves the books with the specified IDs in a single request and lists any that were not found.
+Example: gcrudcli get --ids <book-id>,<book-id>`),
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ids, _ := cmd.Flags().GetStringSlice("ids")
+
+		if len(ids) == 0 {
+			fmt.Println(errorColor("❌ Error: --ids is required"))
+			return
+		}
+
+		query := url.Values{}
+		query.Set("ids", strings.Join(ids, ","))
+		resp, err := http.Get(baseURL + "/books?" + query.Encode())
+		if err != nil {
+			fmt.Println(errorColor("❌ Error fetching books:", err))
+			return
+		}
+		defer resp.Body.Close()
+
+		if err := checkResponse(resp); err != nil {
+			fmt.Println(errorColor("❌ Error:", err))
+			return
+		}
+
+		var result struct {
+			Data    []Book   `json:"data"`
+			Missing []string `json:"missing"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			fmt.Println(errorColor("❌ Error decoding response:", err))
+			return
+		}
+
+		for _, book := range result.Data {
 			fmt.Printf("%s\n", headerColor("📖 Book Details:"))
 			fmt.Printf("🔑 ID: %s\n", infoColor(book.ID))
 			fmt.Printf("📕 Title: %s\n", infoColor(book.Title))
 			fmt.Printf("✍️  Author: %s\n", infoColor(book.Author))
 			fmt.Printf("📅 Year: %d\n\n", book.Year)
 		}
+		for _, id := range result.Missing {
+			fmt.Printf("%s %s\n", errorColor("❌ Book not found:"), infoColor(id))
+		}
 	},
 }
 
@@ -201,7 +405,15 @@ Example: gcrudcli create --title "Book Title" --author "Author Name" --year 2024
 			return
 		}
 
-		resp, err := http.Post(baseURL+"/books", "application/json", bytes.NewBuffer(jsonData))
+		req, err := http.NewRequest(http.MethodPost, baseURL+"/books", bytes.NewBuffer(jsonData))
+		if err != nil {
+			fmt.Println(errorColor("❌ Error creating request:", err))
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Actor", actorName())
+
+		resp, err := http.DefaultClient.Do(req)
 		if err != nil {
 			fmt.Println(errorColor("❌ Error creating book:", err))
 			return
@@ -240,10 +452,13 @@ Example: gcrudcli update --id <book-id> --title "New Title" --author "New Author
 		year, _ := cmd.Flags().GetInt("year")
 
 		var err error
+		// ETag of the book as read, sent back so the server rejects the
+		// update if someone else changed the book in the meantime
+		var etag string
 
 		// Handle ID validation
 		if id == "" {
-			id, err = getValidBookID("🔑 Enter book ID")
+			id, etag, err = getValidBookID("🔑 Enter book ID")
 			if err != nil {
 				return // Don't print error message since getValidBookID already did
 			}
@@ -266,6 +481,7 @@ Example: gcrudcli update --id <book-id> --title "New Title" --author "New Author
 				fmt.Println(errorColor("❌ Error: Invalid book ID or server error"))
 				return
 			}
+			etag = resp.Header.Get("ETag")
 		}
 
 		// Get update details
@@ -325,6 +541,10 @@ Example: gcrudcli update --id <book-id> --title "New Title" --author "New Author
 			return
 		}
 		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Actor", actorName())
+		if etag != "" {
+			req.Header.Set("If-Match", etag)
+		}
 
 		client := &http.Client{}
 		resp, err := client.Do(req)
@@ -336,6 +556,9 @@ Example: gcrudcli update --id <book-id> --title "New Title" --author "New Author
 
 		if err := checkResponse(resp); err != nil {
 			fmt.Println(errorColor("❌ Error:", err))
+			if resp.StatusCode == http.StatusPreconditionFailed {
+				fmt.Println(infoColor("💡 Hint: The book was changed by someone else. Run the update again to edit the latest version."))
+			}
 			return
 		}
 
@@ -350,7 +573,7 @@ Example: gcrudcli update --id <book-id> --title "New Title" --author "New Author
 var deleteCmd = &cobra.Command{
 	Use:   "delete",
 	Short: infoColor("Delete a book"),
-	Long: infoColor(`Delete removes a book from the database with the specified ID.
+	Long: infoColor(`Delete moves the book with the specified ID to the trash, from where it can be restored.
 Example: gcrudcli delete --id <book-id>`),
 	Args: cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
@@ -377,7 +600,7 @@ Example: gcrudcli delete --id <book-id>`),
 				return
 			}
 		} else {
-			id, err = getValidBookID("🔑 Enter book ID to delete")
+			id, _, err = getValidBookID("🔑 Enter book ID to delete")
 			if err != nil {
 				return // Don't show error message since getValidBookID already did
 			}
@@ -388,6 +611,7 @@ Example: gcrudcli delete --id <book-id>`),
 			fmt.Println(errorColor("❌ Error creating request:", err))
 			return
 		}
+		req.Header.Set("X-Actor", actorName())
 
 		client := &http.Client{}
 		resp, err := client.Do(req)
@@ -402,12 +626,283 @@ Example: gcrudcli delete --id <book-id>`),
 			return
 		}
 
-		fmt.Printf("%s %s %s\n", successColor("🗑️  Book with ID"), infoColor(id), successColor("deleted successfully ✨"))
+		fmt.Printf("%s %s %s\n", successColor("🗑️  Book with ID"), infoColor(id), successColor("moved to trash ✨"))
+		fmt.Println(infoColor(fmt.Sprintf("💡 Hint: Use 'restore --id %s' to undo.", id)))
+	},
+}
+
+var trashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: infoColor("List deleted books"),
+	Long: infoColor(`Trash lists the books that were deleted and can still be restored, most recently deleted first.
+Example: gcrudcli trash --page 2 --limit 10`),
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		page, _ := cmd.Flags().GetInt("page")
+		limit, _ := cmd.Flags().GetInt("limit")
+
+		query := url.Values{}
+		query.Set("page", strconv.Itoa(page))
+		query.Set("limit", strconv.Itoa(limit))
+		query.Set("sort", "-deletedAt")
+
+		resp, err := http.Get(baseURL + "/books/trash?" + query.Encode())
+		if err != nil {
+			fmt.Println(errorColor("❌ Error fetching trash:", err))
+			return
+		}
+		defer resp.Body.Close()
+
+		if err := checkResponse(resp); err != nil {
+			fmt.Println(errorColor("❌ Error:", err))
+			return
+		}
+
+		var bookPage BookPage
+		if err := json.NewDecoder(resp.Body).Decode(&bookPage); err != nil {
+			fmt.Println(errorColor("❌ Error decoding response:", err))
+			return
+		}
+
+		if len(bookPage.Data) == 0 {
+			fmt.Println(infoColor("🗑️  Trash is empty"))
+			return
+		}
+
+		for _, book := range bookPage.Data {
+			fmt.Printf("%s\n", headerColor("📖 Book Details:"))
+			fmt.Printf("🔑 ID: %s\n", infoColor(book.ID))
+			fmt.Printf("📕 Title: %s\n", infoColor(book.Title))
+			fmt.Printf("✍️  Author: %s\n", infoColor(book.Author))
+			fmt.Printf("📅 Year: %d\n", book.Year)
+			fmt.Printf("🗑️  Deleted: %s\n\n", infoColor(book.DeletedAt.Local().Format(time.DateTime)))
+		}
+
+		fmt.Printf("📄 Page %d, showing %d of %d deleted books\n", bookPage.Page, len(bookPage.Data), bookPage.Total)
+		if bookPage.Links.Next != "" {
+			fmt.Println(infoColor(fmt.Sprintf("💡 Hint: Use \"%s\" to see the next page.", nextPageCommand(cmd, "page", strconv.FormatInt(bookPage.Page+1, 10)))))
+		}
+	},
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: infoColor("Restore a deleted book"),
+	Long: infoColor(`Restore takes the book with the specified ID out of the trash.
+Example: gcrudcli restore --id <book-id>`),
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		id, _ := cmd.Flags().GetString("id")
+
+		var err error
+		if id == "" {
+			id, err = getStringInput("🔑 Enter book ID to restore", "")
+			if err != nil {
+				fmt.Println(errorColor("❌ Error getting ID:", err))
+				return
+			}
+		}
+
+		req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/books/%s/restore", baseURL, id), nil)
+		if err != nil {
+			fmt.Println(errorColor("❌ Error creating request:", err))
+			return
+		}
+		req.Header.Set("X-Actor", actorName())
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			fmt.Println(errorColor("❌ Error restoring book:", err))
+			return
+		}
+		defer resp.Body.Close()
+
+		if err := checkResponse(resp); err != nil {
+			fmt.Println(errorColor("❌ Error:", err))
+			if resp.StatusCode == http.StatusNotFound {
+				fmt.Println(infoColor("💡 Hint: Use the 'trash' command to see deleted book IDs."))
+			}
+			return
+		}
+
+		fmt.Printf("%s %s %s\n", successColor("♻️  Book with ID"), infoColor(id), successColor("restored successfully ✨"))
+	},
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: infoColor("Import books from a JSON file"),
+	Long: infoColor(`Import creates every book in a JSON file holding an array of books, sending them in bulk requests of up to 1000.
+With --atomic each batch is created entirely or not at all.
+Example: gcrudcli import --file books.json`),
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		file, _ := cmd.Flags().GetString("file")
+		atomic, _ := cmd.Flags().GetBool("atomic")
+
+		if file == "" {
+			fmt.Println(errorColor("❌ Error: --file is required"))
+			return
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Println(errorColor("❌ Error reading file:", err))
+			return
+		}
+
+		var books []Book
+		if err := json.Unmarshal(data, &books); err != nil {
+			fmt.Println(errorColor("❌ Error: file must hold a JSON array of books:", err))
+			return
+		}
+
+		created, failed := 0, 0
+		for start := 0; start < len(books); start += bulkBatchSize {
+			batch := books[start:min(start+bulkBatchSize, len(books))]
+
+			ops := make([]map[string]any, len(batch))
+			for i, book := range batch {
+				ops[i] = map[string]any{"op": "create", "book": book}
+			}
+			jsonData, err := json.Marshal(ops)
+			if err != nil {
+				fmt.Println(errorColor("❌ Error encoding books:", err))
+				return
+			}
+
+			req, err := http.NewRequest(http.MethodPost,
+				fmt.Sprintf("%s/books/_bulk?atomic=%t", baseURL, atomic), bytes.NewBuffer(jsonData))
+			if err != nil {
+				fmt.Println(errorColor("❌ Error creating request:", err))
+				return
+			}
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Actor", actorName())
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				fmt.Println(errorColor("❌ Error importing books:", err))
+				return
+			}
+
+			var bulk BulkResponse
+			err = checkResponse(resp)
+			if err == nil {
+				err = json.NewDecoder(resp.Body).Decode(&bulk)
+			}
+			resp.Body.Close()
+			if err != nil {
+				fmt.Println(errorColor("❌ Error:", err))
+				return
+			}
+
+			for i, result := range bulk.Results {
+				if result.Error == nil {
+					created++
+					continue
+				}
+				failed++
+				if result.Status != http.StatusFailedDependency {
+					fmt.Printf("%s %s\n", errorColor(fmt.Sprintf("❌ Book %d (%q):", start+i+1, batch[i].Title)),
+						result.Error.Detail)
+					for _, fe := range result.Error.Errors {
+						fmt.Printf("   • %s: %s\n", fe.Field, fe.Message)
+					}
+				}
+			}
+			if bulk.RolledBack {
+				fmt.Println(errorColor(fmt.Sprintf("↩️  Books %d to %d were not imported because one of them failed",
+					start+1, start+len(batch))))
+			}
+		}
+
+		fmt.Printf("%s %s", successColor("📚 Imported"), infoColor(created))
+		if failed > 0 {
+			fmt.Printf(", %s %s", errorColor("failed"), infoColor(failed))
+		}
+		fmt.Println()
+	},
+}
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: infoColor("Show the change history of a book"),
+	Long: infoColor(`History lists every change made to the book with the specified ID: who made it, when, and which fields changed.
+Example: gcrudcli history --id <book-id>`),
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		id, _ := cmd.Flags().GetString("id")
+
+		var err error
+		if id == "" {
+			id, err = getStringInput("🔑 Enter book ID", "")
+			if err != nil {
+				fmt.Println(errorColor("❌ Error getting ID:", err))
+				return
+			}
+		}
+
+		resp, err := http.Get(fmt.Sprintf("%s/books/%s/history", baseURL, id))
+		if err != nil {
+			fmt.Println(errorColor("❌ Error fetching history:", err))
+			return
+		}
+		defer resp.Body.Close()
+
+		if err := checkResponse(resp); err != nil {
+			fmt.Println(errorColor("❌ Error:", err))
+			return
+		}
+
+		var history struct {
+			Data []HistoryEntry `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&history); err != nil {
+			fmt.Println(errorColor("❌ Error decoding response:", err))
+			return
+		}
+
+		if len(history.Data) == 0 {
+			fmt.Println(infoColor("📜 No recorded changes"))
+			return
+		}
+
+		for _, entry := range history.Data {
+			fmt.Printf("%s %s %s %s\n", headerColor("📜 v"+strconv.FormatInt(entry.Version, 10)),
+				infoColor(entry.Action), "by", infoColor(entry.Actor))
+			fmt.Printf("🕒 %s\n", entry.Timestamp.Local().Format(time.DateTime))
+			for _, change := range entry.Changes {
+				fmt.Printf("   • %s: %s → %s\n", change.Field, formatValue(change.Before), formatValue(change.After))
+			}
+			fmt.Println()
+		}
 	},
 }
 
+// formatValue prints a history field value, showing absent ones as "—".
+func formatValue(v any) string {
+	if v == nil {
+		return "—"
+	}
+	return fmt.Sprint(v)
+}
+
 func init() {
-	rootCmd.AddCommand(fetchCmd, createCmd, updateCmd, deleteCmd)
+	rootCmd.AddCommand(fetchCmd, getCmd, createCmd, updateCmd, deleteCmd, trashCmd, restoreCmd, historyCmd, importCmd)
+
+	// Add flags for fetch command
+	fetchCmd.Flags().Int("page", 1, "Page number to fetch")
+	fetchCmd.Flags().Int("limit", 20, "Number of books per page")
+	fetchCmd.Flags().String("sort", "", "Comma-separated sort fields, prefix with - for descending (e.g. title,-year)")
+	fetchCmd.Flags().String("title", "", "Only show books with this exact title")
+	fetchCmd.Flags().String("author", "", "Only show books by this exact author")
+	fetchCmd.Flags().Int("year-gte", 0, "Only show books published in or after this year")
+	fetchCmd.Flags().Int("year-lte", 0, "Only show books published in or before this year")
+	fetchCmd.Flags().String("cursor", "", "Cursor from a previous fetch; pass \"\" for the first page")
+
+	// Add flags for get command
+	getCmd.Flags().StringSlice("ids", nil, "Comma-separated IDs of the books to get")
 
 	// Add flags for create command
 	createCmd.Flags().String("title", "", "Title of the book")
@@ -422,6 +917,20 @@ func init() {
 
 	// Add flags for delete command
 	deleteCmd.Flags().String("id", "", "ID of the book to delete")
+
+	// Add flags for trash command
+	trashCmd.Flags().Int("page", 1, "Page number to fetch")
+	trashCmd.Flags().Int("limit", 20, "Number of books per page")
+
+	// Add flags for restore command
+	restoreCmd.Flags().String("id", "", "ID of the book to restore")
+
+	// Add flags for import command
+	importCmd.Flags().String("file", "", "JSON file holding an array of books")
+	importCmd.Flags().Bool("atomic", false, "Import each batch entirely or not at all")
+
+	// Add flags for history command
+	historyCmd.Flags().String("id", "", "ID of the book whose history to show")
 }
 
 func main() {
@@ -1,6 +1,6 @@
-import { useEffect, useState } from 'react';
+import { useEffect, useRef, useState } from 'react';
 import { View, Text, StyleSheet, ScrollView, TextInput, TouchableOpacity, Alert, ActivityIndicator } from 'react-native';
-import { BookService, Book } from '../../services/BookService';
+import { BookService, Book, newIdempotencyKey } from '../../services/BookService';
 
 export default function BooksScreen() {
     const [books, setBooks] = useState<Book[]>([]);
@@ -8,11 +8,18 @@ export default function BooksScreen() {
     const [editingBook, setEditingBook] = useState<Book | null>(null);
     const [isLoading, setIsLoading] = useState(false);
     const [isSubmitting, setIsSubmitting] = useState(false);
+    // Idempotency key of the book being added, kept across retries of the
+    // same form and replaced once the form changes
+    const createKey = useRef<string | null>(null);
 
     useEffect(() => {
         loadBooks();
     }, []);
 
+    useEffect(() => {
+        createKey.current = null;
+    }, [newBook]);
+
     const loadBooks = async () => {
         setIsLoading(true);
         try {
@@ -34,7 +41,8 @@ export default function BooksScreen() {
 
         setIsSubmitting(true);
         try {
-            const addedBook = await BookService.createBook(newBook);
+            createKey.current ??= newIdempotencyKey();
+            const addedBook = await BookService.createBook(newBook, createKey.current);
             setBooks(prevBooks => [...prevBooks, addedBook]);
             setNewBook({ title: '', author: '', year: 0 });
             Alert.alert('Success', 'Book added successfully!');
@@ -35,11 +35,23 @@ axios.interceptors.response.use(
     }
 );
 
+// newIdempotencyKey returns a random key for the Idempotency-Key header.
+export const newIdempotencyKey = (): string =>
+    `${Date.now().toString(36)}-${Math.random().toString(36).slice(2)}${Math.random().toString(36).slice(2)}`;
+
 export const BookService = {
     getBooks: async (): Promise<Book[]> => {
         try {
-            const response = await axios.get(API_URL);
-            return response.data.map((book: Book) => ({
+            // Follow the pages until the last one, with cursors so that
+            // books added or deleted meanwhile do not shift them
+            const books: Book[] = [];
+            let response = await axios.get(API_URL, { params: { limit: 100, cursor: '' } });
+            books.push(...response.data.data);
+            while (response.data.links?.next) {
+                response = await axios.get(`${BASE_URL}${response.data.links.next}`);
+                books.push(...response.data.data);
+            }
+            return books.map((book: Book) => ({
                 ...book,
                 id: book._id || book.id // Handle both id formats
             }));
@@ -49,9 +61,12 @@ export const BookService = {
         }
     },
 
-    createBook: async (book: Book): Promise<Book> => {
+    // Pass the same idempotencyKey when retrying a create so the server
+    // replays its first response instead of adding the book twice
+    createBook: async (book: Book, idempotencyKey?: string): Promise<Book> => {
         try {
-            const response = await axios.post(API_URL, book);
+            const headers = idempotencyKey ? { 'Idempotency-Key': idempotencyKey } : undefined;
+            const response = await axios.post(API_URL, book, { headers });
             return {
                 ...response.data,
                 id: response.data._id || response.data.id
@@ -83,10 +98,10 @@ export const BookService = {
             const url = `${API_URL}/${id}`.replace(/\/+$/, '');
             const response = await axios.delete(url);
             if (response.status !== 200) {
-                throw new Error(response.data?.error || 'Failed to delete book');
+                throw new Error(response.data?.detail || 'Failed to delete book');
             }
         } catch (error: any) {
-            console.error('Error deleting book:', error.response?.data?.error || error.message);
+            console.error('Error deleting book:', error.response?.data?.detail || error.message);
             throw error;
         }
     }
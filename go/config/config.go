@@ -0,0 +1,459 @@
+// Package config loads the server configuration from a YAML or TOML file,
+// environment variables and command-line flags.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"gopkg.in/yaml.v3"
+)
+
+// envPrefix starts the environment variable of every setting, e.g.
+// GOCRUD_MONGO_URI for -mongo-uri.
+const envPrefix = "GOCRUD_"
+
+// Config is the server configuration. Its file keys are the yaml and toml
+// tags below.
+type Config struct {
+	Server ServerConfig `yaml:"server" toml:"server"`
+	Store  StoreConfig  `yaml:"store" toml:"store"`
+	Mongo  MongoConfig  `yaml:"mongo" toml:"mongo"`
+	Health HealthConfig `yaml:"health" toml:"health"`
+	// Metrics turns on /metrics, which serves Prometheus metrics about
+	// requests, store calls and the MongoDB connection pool.
+	Metrics bool      `yaml:"metrics" toml:"metrics"`
+	Log     LogConfig `yaml:"log" toml:"log"`
+}
+
+type ServerConfig struct {
+	// Addr is the address the HTTP server listens on.
+	Addr string `yaml:"addr" toml:"addr"`
+	// ShutdownTimeout is how long requests in flight get to finish after
+	// a shutdown signal before they are canceled.
+	ShutdownTimeout Duration `yaml:"shutdownTimeout" toml:"shutdownTimeout"`
+	// CORSOrigins lists the origins allowed by CORS; empty disables CORS.
+	CORSOrigins []string `yaml:"corsOrigins" toml:"corsOrigins"`
+	// AdminToken is the bearer token for admin-only operations; empty
+	// disables them.
+	AdminToken string `yaml:"adminToken" toml:"adminToken"`
+	// IdempotencyTTL is how long responses to POST /books with an
+	// Idempotency-Key are kept for replay.
+	IdempotencyTTL Duration `yaml:"idempotencyTTL" toml:"idempotencyTTL"`
+}
+
+type StoreConfig struct {
+	// Kind is the book store to use: mongo or memory.
+	Kind string `yaml:"kind" toml:"kind"`
+	// Timeout bounds each store call made for a request.
+	Timeout Duration `yaml:"timeout" toml:"timeout"`
+	// TrashRetention is how long deleted books stay in the trash before
+	// they are purged; zero keeps them forever.
+	TrashRetention Duration `yaml:"trashRetention" toml:"trashRetention"`
+}
+
+type MongoConfig struct {
+	URI      string `yaml:"uri" toml:"uri"`
+	Database string `yaml:"database" toml:"database"`
+	// ConnectTimeout bounds each attempt to reach MongoDB at startup.
+	ConnectTimeout Duration `yaml:"connectTimeout" toml:"connectTimeout"`
+	// ConnectRetry spaces out the attempts to reach MongoDB at startup.
+	ConnectRetry RetryConfig `yaml:"connectRetry" toml:"connectRetry"`
+	// StartDegraded starts serving before MongoDB is reachable, answering
+	// the book endpoints with 503 until it is, instead of waiting for it.
+	// The server then retries forever, whatever ConnectRetry.Attempts.
+	StartDegraded bool `yaml:"startDegraded" toml:"startDegraded"`
+}
+
+// RetryConfig configures retries with exponential backoff and jitter.
+type RetryConfig struct {
+	// Attempts is the most attempts made; zero retries forever.
+	Attempts int `yaml:"attempts" toml:"attempts"`
+	// InitialBackoff is the wait after the first failure, doubled after
+	// each further one up to MaxBackoff.
+	InitialBackoff Duration `yaml:"initialBackoff" toml:"initialBackoff"`
+	MaxBackoff     Duration `yaml:"maxBackoff" toml:"maxBackoff"`
+}
+
+type HealthConfig struct {
+	// Timeout bounds each dependency check of /readyz.
+	Timeout Duration `yaml:"timeout" toml:"timeout"`
+	// Detail is how much /readyz tells about its checks: none for the
+	// overall status only, status to add each component's status and
+	// latency, full to also say why a component is down.
+	Detail string `yaml:"detail" toml:"detail"`
+}
+
+type LogConfig struct {
+	// Level is the least severe level logged: debug, info, warn or error.
+	Level string `yaml:"level" toml:"level"`
+	// Format is json for one JSON object per line, or text for key=value
+	// pairs.
+	Format string `yaml:"format" toml:"format"`
+}
+
+// Duration is a time.Duration that files spell like "10s" or "24h".
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+// UnmarshalYAML also accepts unquoted scalars such as 0, which YAML would
+// otherwise decode as numbers.
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	return d.UnmarshalText([]byte(node.Value))
+}
+
+// Default returns the configuration used for settings that are not set
+// anywhere else, which suits a development machine.
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Addr:            ":8080",
+			ShutdownTimeout: Duration{15 * time.Second},
+			// "*" lets any other UI, such as the Angular one on
+			// localhost:4200, call a development server
+			CORSOrigins: []string{"http://localhost:19000", "http://localhost:19006",
+				"http://192.168.100.34:19006", "*"},
+			IdempotencyTTL: Duration{24 * time.Hour},
+		},
+		Store: StoreConfig{
+			Kind:           "mongo",
+			Timeout:        Duration{10 * time.Second},
+			TrashRetention: Duration{30 * 24 * time.Hour},
+		},
+		Mongo: MongoConfig{
+			URI:            "mongodb://localhost:27017",
+			Database:       "library",
+			ConnectTimeout: Duration{5 * time.Second},
+			ConnectRetry: RetryConfig{
+				Attempts:       10,
+				InitialBackoff: Duration{500 * time.Millisecond},
+				MaxBackoff:     Duration{30 * time.Second},
+			},
+		},
+		Health: HealthConfig{
+			Timeout: Duration{2 * time.Second},
+			Detail:  "status",
+		},
+		Metrics: true,
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+	}
+}
+
+// setting is a configuration value that can be set with a flag and an
+// environment variable.
+type setting struct {
+	flag  string
+	usage string
+	set   func(c *Config, v string) error
+}
+
+// legacyEnv maps flags to the older environment variables that still set
+// them, at a lower precedence than the GOCRUD_ ones.
+var legacyEnv = map[string]string{
+	"admin-token": "ADMIN_TOKEN",
+}
+
+// boolFlags lists the settings that are booleans, so that their flag can
+// be given without a value.
+var boolFlags = map[string]bool{
+	"mongo-start-degraded": true,
+	"metrics":              true,
+}
+
+var settings = []setting{
+	{"addr", "address the HTTP server listens on", setString(func(c *Config) *string { return &c.Server.Addr })},
+	{"shutdown-timeout", "how long requests in flight get to finish on SIGINT or SIGTERM", setDuration(func(c *Config) *Duration { return &c.Server.ShutdownTimeout })},
+	{"cors-origins", "comma-separated origins allowed by CORS; empty disables CORS", setList(func(c *Config) *[]string { return &c.Server.CORSOrigins })},
+	{"admin-token", "bearer token for admin-only operations such as purging books", setString(func(c *Config) *string { return &c.Server.AdminToken })},
+	{"idempotency-ttl", "how long responses to POST /books with an Idempotency-Key are kept for replay", setDuration(func(c *Config) *Duration { return &c.Server.IdempotencyTTL })},
+	{"store", "book store to use: mongo or memory", setString(func(c *Config) *string { return &c.Store.Kind })},
+	{"db-timeout", "timeout of each store call made for a request", setDuration(func(c *Config) *Duration { return &c.Store.Timeout })},
+	{"trash-retention", "how long deleted books stay in the trash before they are purged; 0 keeps them forever", setDuration(func(c *Config) *Duration { return &c.Store.TrashRetention })},
+	{"mongo-uri", "MongoDB connection string", setString(func(c *Config) *string { return &c.Mongo.URI })},
+	{"mongo-database", "MongoDB database holding the books", setString(func(c *Config) *string { return &c.Mongo.Database })},
+	{"mongo-connect-timeout", "timeout of each attempt to reach MongoDB at startup", setDuration(func(c *Config) *Duration { return &c.Mongo.ConnectTimeout })},
+	{"mongo-connect-attempts", "most attempts to reach MongoDB at startup; 0 retries forever, as does -mongo-start-degraded", setInt(func(c *Config) *int { return &c.Mongo.ConnectRetry.Attempts })},
+	{"mongo-connect-backoff", "wait after the first failed attempt to reach MongoDB, doubled after each further one", setDuration(func(c *Config) *Duration { return &c.Mongo.ConnectRetry.InitialBackoff })},
+	{"mongo-connect-max-backoff", "longest wait between attempts to reach MongoDB", setDuration(func(c *Config) *Duration { return &c.Mongo.ConnectRetry.MaxBackoff })},
+	{"mongo-start-degraded", "serve before MongoDB is reachable, answering the book endpoints with 503 until it is, and retry reaching it forever", setBool(func(c *Config) *bool { return &c.Mongo.StartDegraded })},
+	{"health-timeout", "timeout of each dependency check of /readyz", setDuration(func(c *Config) *Duration { return &c.Health.Timeout })},
+	{"health-detail", "what /readyz tells about its checks: none, status or full", setString(func(c *Config) *string { return &c.Health.Detail })},
+	{"metrics", "serve Prometheus metrics under /metrics", setBool(func(c *Config) *bool { return &c.Metrics })},
+	{"log-level", "least severe level logged: debug, info, warn or error", setString(func(c *Config) *string { return &c.Log.Level })},
+	{"log-format", "log format: json or text", setString(func(c *Config) *string { return &c.Log.Format })},
+}
+
+func setString(field func(*Config) *string) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		*field(c) = v
+		return nil
+	}
+}
+
+func setInt(field func(*Config) *int) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return errors.New("not an integer")
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+func setBool(field func(*Config) *bool) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return errors.New("not a boolean")
+		}
+		*field(c) = b
+		return nil
+	}
+}
+
+func setList(field func(*Config) *[]string) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		var list []string
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		*field(c) = list
+		return nil
+	}
+}
+
+func setDuration(field func(*Config) *Duration) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		return field(c).UnmarshalText([]byte(v))
+	}
+}
+
+// envName returns the environment variable of the setting with the given
+// flag.
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// Load defines the configuration flags on fs, parses args with it and
+// returns the configuration. Each setting comes from, in increasing order
+// of precedence: Default, the file named by -config or $GOCRUD_CONFIG, its
+// environment variable and its flag. Load does not validate the result.
+func Load(fs *flag.FlagSet, args []string) (Config, error) {
+	path := fs.String("config", "", "YAML or TOML configuration file (env "+envName("config")+")")
+
+	// Flags are applied last, so only record them while parsing
+	flagValues := map[string]string{}
+	for _, s := range settings {
+		env := envName(s.flag)
+		if legacy, ok := legacyEnv[s.flag]; ok {
+			env += " or " + legacy
+		}
+		record := func(v string) error {
+			if err := s.set(&Config{}, v); err != nil {
+				return err
+			}
+			flagValues[s.flag] = v
+			return nil
+		}
+		usage := fmt.Sprintf("%s (env %s)", s.usage, env)
+		if boolFlags[s.flag] {
+			fs.BoolFunc(s.flag, usage, record)
+		} else {
+			fs.Func(s.flag, usage, record)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	cfg := Default()
+	if *path == "" {
+		*path = os.Getenv(envName("config"))
+	}
+	if *path != "" {
+		if err := loadFile(&cfg, *path); err != nil {
+			return Config{}, err
+		}
+	}
+
+	for _, s := range settings {
+		for _, name := range []string{legacyEnv[s.flag], envName(s.flag)} {
+			v := os.Getenv(name)
+			if name == "" || v == "" {
+				continue
+			}
+			if err := s.set(&cfg, v); err != nil {
+				return Config{}, fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+
+	for _, s := range settings {
+		if v, ok := flagValues[s.flag]; ok {
+			s.set(&cfg, v)
+		}
+	}
+	return cfg, nil
+}
+
+// loadFile overlays the settings in the file at path on cfg. The format
+// follows the extension, and unknown keys are an error.
+func loadFile(cfg *Config, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("reading config: %w", err)
+	}
+	defer f.Close()
+
+	switch ext := filepath.Ext(path); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(f)
+		dec.KnownFields(true)
+		err = dec.Decode(cfg)
+		if errors.Is(err, io.EOF) {
+			// An empty file sets nothing
+			err = nil
+		}
+	case ".toml":
+		dec := toml.NewDecoder(f)
+		dec.DisallowUnknownFields()
+		err = dec.Decode(cfg)
+	default:
+		return fmt.Errorf("config file %s: unknown format %q, expected .yaml, .yml or .toml", path, ext)
+	}
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate reports every invalid setting of c.
+func (c Config) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if _, port, err := net.SplitHostPort(c.Server.Addr); err != nil {
+		invalid("server.addr: %v", err)
+	} else if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		invalid("server.addr: invalid port %q", port)
+	}
+	if c.Server.ShutdownTimeout.Duration < 0 {
+		invalid("server.shutdownTimeout cannot be negative")
+	}
+	for _, origin := range c.Server.CORSOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			invalid("server.corsOrigins: %q is not * or an http(s) origin", origin)
+		}
+	}
+	if c.Server.IdempotencyTTL.Duration <= 0 {
+		invalid("server.idempotencyTTL must be positive")
+	}
+
+	if c.Store.Timeout.Duration <= 0 {
+		invalid("store.timeout must be positive")
+	}
+	if c.Store.TrashRetention.Duration < 0 {
+		invalid("store.trashRetention cannot be negative")
+	}
+
+	if c.Health.Timeout.Duration <= 0 {
+		invalid("health.timeout must be positive")
+	}
+	switch c.Health.Detail {
+	case "none", "status", "full":
+	default:
+		invalid("health.detail: unknown level %q, expected none, status or full", c.Health.Detail)
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+		invalid("log.level: unknown level %q, expected debug, info, warn or error", c.Log.Level)
+	}
+	switch c.Log.Format {
+	case "json", "text":
+	default:
+		invalid("log.format: unknown format %q, expected json or text", c.Log.Format)
+	}
+
+	switch c.Store.Kind {
+	case "memory":
+	case "mongo":
+		if err := options.Client().ApplyURI(c.Mongo.URI).Validate(); err != nil {
+			invalid("mongo.uri: %v", err)
+		}
+		if c.Mongo.Database == "" {
+			invalid("mongo.database is required")
+		}
+		if c.Mongo.ConnectTimeout.Duration <= 0 {
+			invalid("mongo.connectTimeout must be positive")
+		}
+		if retry := c.Mongo.ConnectRetry; retry.Attempts < 0 {
+			invalid("mongo.connectRetry.attempts cannot be negative")
+		} else if retry.InitialBackoff.Duration <= 0 || retry.MaxBackoff.Duration < retry.InitialBackoff.Duration {
+			invalid("mongo.connectRetry needs 0 < initialBackoff <= maxBackoff")
+		}
+	default:
+		invalid("store.kind: unknown store %q, expected mongo or memory", c.Store.Kind)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Print writes c to w as a YAML config file, with the admin token and any
+// password in the MongoDB URI redacted.
+func (c Config) Print(w io.Writer) error {
+	if c.Server.AdminToken != "" {
+		c.Server.AdminToken = "REDACTED"
+	}
+	if u, err := url.Parse(c.Mongo.URI); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "REDACTED")
+			c.Mongo.URI = u.String()
+		}
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+	return enc.Close()
+}
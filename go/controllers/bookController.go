@@ -2,169 +2,277 @@ package controllers
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/v2/bson"
 
-	"go.mongodb.org/mongo-driver/v2/mongo"
-
 	"go-crud/models"
+	"go-crud/store"
 )
 
-var bookCollection *mongo.Collection
+// Defaults for the zero values of ControllerOptions.
+const (
+	defaultTimeout        = 10 * time.Second
+	defaultIdempotencyTTL = 24 * time.Hour
+)
 
-func InitBookController(db *mongo.Database) {
-	bookCollection = db.Collection("books")
+// ControllerOptions configures a BookController.
+type ControllerOptions struct {
+	// Timeout bounds each store call; zero means defaultTimeout.
+	Timeout time.Duration
+	// AdminToken is the bearer token that grants admin-only operations
+	// such as purging books; empty disables them.
+	AdminToken string
+	// IdempotencyTTL is how long responses to requests with an
+	// Idempotency-Key are kept for replay; zero means
+	// defaultIdempotencyTTL.
+	IdempotencyTTL time.Duration
+	// Ready reports whether the store can serve requests; until it does
+	// the book endpoints answer 503. Nil means always ready.
+	Ready func() bool
+}
+
+// BookController serves the books API on top of a BookStore.
+type BookController struct {
+	store store.BookStore
+	opts  ControllerOptions
+}
+
+func NewBookController(s store.BookStore, opts ControllerOptions) *BookController {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.IdempotencyTTL <= 0 {
+		opts.IdempotencyTTL = defaultIdempotencyTTL
+	}
+	return &BookController{store: s, opts: opts}
+}
+
+// RegisterRoutes mounts the book endpoints on rg, e.g. under "/books".
+func (bc *BookController) RegisterRoutes(rg *gin.RouterGroup) {
+	rg.Use(ErrorHandler(), bc.requireReady())
+	rg.GET("", bc.GetBooks)
+	rg.GET("/search", bc.SearchBooks)
+	rg.GET("/trash", bc.GetTrash)
+	rg.GET("/:id", bc.GetBook)
+	rg.GET("/:id/history", bc.GetHistory)
+	rg.POST("", bc.idempotent(), bc.CreateBook)
+	rg.POST("/_bulk", bc.BulkBooks)
+	rg.POST("/_mget", bc.GetBooksByID)
+	rg.POST("/:id/restore", bc.RestoreBook)
+	rg.POST("/:id/revert", bc.RevertBook)
+	rg.PUT("/:id", bc.UpdateBook)
+	rg.PATCH("/:id", bc.PatchBook)
+	rg.DELETE("/:id", bc.DeleteBook)
 }
-func GetBooks(c *gin.Context) {
-	// Set a timeout for the database operation
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
 
-	// Find all books in the collection
-	cursor, err := bookCollection.Find(ctx, bson.M{})
+// GetBooks lists books, or with ?ids=a,b,c gets the books with those IDs.
+// Both take ?fields=title,author to send only some fields of each book.
+func (bc *BookController) GetBooks(c *gin.Context) {
+	q := c.Request.URL.Query()
+	if q.Has("ids") {
+		fields, err := parseFields(q.Get("fields"))
+		if err != nil {
+			c.Error(badRequest(err.Error()))
+			return
+		}
+		q.Del("fields")
+		if len(q) > 1 {
+			c.Error(badRequest("ids can only be combined with fields"))
+			return
+		}
+		body, err := bc.batchGet(c, strings.Split(q.Get("ids"), ","), fields)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		// No Last-Modified: a book moving to missing when it is deleted
+		// leaves the newest updatedAt of the others as it was
+		respondConditional(c, body, time.Time{})
+		return
+	}
+	bc.listBooks(c, false)
+}
+
+// listBooks serves a page of the live books or of the trash.
+func (bc *BookController) listBooks(c *gin.Context, trashed bool) {
+	lq, err := parseListQuery(c.Request.URL.Query())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch books"})
+		c.Error(badRequest(err.Error()))
 		return
 	}
-	defer cursor.Close(ctx)
+	lq.opts.Trashed = trashed
+
+	// Set a timeout for the database operation
+	ctx, cancel := context.WithTimeout(c.Request.Context(), bc.opts.Timeout)
+	defer cancel()
 
-	var books []models.Book
-	if err := cursor.All(ctx, &books); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode books"})
+	res, err := bc.store.List(ctx, lq.opts)
+	if err != nil {
+		c.Error(storeError("Failed to fetch books", err))
 		return
 	}
 
-	c.JSON(http.StatusOK, books)
+	// No Last-Modified: creates, deletes and books moving between pages
+	// change a page without changing the updatedAt of any book on it, so
+	// only the ETag can tell whether a page is current
+	respondConditional(c, newBookPage(c.Request.URL, lq, res), time.Time{})
 }
 
-func GetBook(c *gin.Context) {
+// GetBook returns a book, or with ?as_of=<RFC 3339 time> the revision of
+// it that was current at that time. ?fields= limits the fields sent.
+func (bc *BookController) GetBook(c *gin.Context) {
 	bookID := c.Param("id")
 	objectID, err := bson.ObjectIDFromHex(bookID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid book ID format"})
+		c.Error(badRequest("Invalid book ID format"))
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	fields, err := parseFields(c.Query("fields"))
+	if err != nil {
+		c.Error(badRequest(err.Error()))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), bc.opts.Timeout)
 	defer cancel()
 
 	var book models.Book
-	err = bookCollection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&book)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find book"})
+	if v := c.Query("as_of"); v != "" {
+		asOf, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.Error(badRequest("as_of must be an RFC 3339 timestamp"))
+			return
+		}
+		book, err = bc.bookAsOf(ctx, objectID, asOf)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+	} else {
+		book, err = bc.store.Get(ctx, objectID)
+		if err != nil {
+			c.Error(storeError("Failed to find book", err))
+			return
 		}
-		return
 	}
 
-	c.JSON(http.StatusOK, book)
+	// Projections send the ETag of the whole book too, so that it can be
+	// used in If-Match like any other
+	data, err := json.Marshal(projectBook(book, fields))
+	if err != nil {
+		c.Error(internalError("Failed to encode response", err))
+		return
+	}
+	respondTagged(c, data, bookETag(book), book.UpdatedAt)
 }
 
-func CreateBook(c *gin.Context) {
+func (bc *BookController) CreateBook(c *gin.Context) {
 	var book models.Book
 	if err := c.ShouldBindJSON(&book); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(bindError(err))
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), bc.opts.Timeout)
 	defer cancel()
 
-	res, err := bookCollection.InsertOne(ctx, book)
+	book, err := bc.store.Create(ctx, book)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(storeError("Failed to create book", err))
+		return
+	}
+	if err := bc.recordHistory(ctx, c, models.ActionCreate, models.Book{}, book); err != nil {
+		c.Error(err)
 		return
 	}
 
-	book.ID = res.InsertedID.(bson.ObjectID)
+	c.Header("ETag", bookETag(book))
 	c.JSON(http.StatusCreated, book)
-
 }
-func UpdateBook(c *gin.Context) {
+
+func (bc *BookController) UpdateBook(c *gin.Context) {
 	bookID := c.Param("id")
 	objectID, err := bson.ObjectIDFromHex(bookID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid book ID"})
-		return
-	}
-
-	// First check if the book exists
-	var existingBook models.Book
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	err = bookCollection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&existingBook)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find book"})
-		}
+		c.Error(badRequest("Invalid book ID"))
 		return
 	}
 
 	var updateData models.Book
 	if err := c.ShouldBindJSON(&updateData); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+		c.Error(bindError(err))
 		return
 	}
 
-	update := bson.M{"$set": bson.M{
-		"title":  updateData.Title,
-		"author": updateData.Author,
-		"year":   updateData.Year,
-	}}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), bc.opts.Timeout)
+	defer cancel()
 
-	_, err = bookCollection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	before, updatedBook, err := bc.modifyBook(ctx, c, objectID, func(current models.Book) (models.Book, error) {
+		book, err := bc.store.Update(ctx, objectID, updateData, current.Version)
+		if err != nil {
+			return models.Book{}, storeError("Failed to update book", err)
+		}
+		return book, nil
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update book"})
+		c.Error(err)
 		return
 	}
-
-	// Return the updated book
-	var updatedBook models.Book
-	err = bookCollection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&updatedBook)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated book"})
+	if err := bc.recordHistory(ctx, c, models.ActionUpdate, before, updatedBook); err != nil {
+		c.Error(err)
 		return
 	}
 
+	// Return the updated book
+	c.Header("ETag", bookETag(updatedBook))
 	c.JSON(http.StatusOK, updatedBook)
 }
 
-func DeleteBook(c *gin.Context) {
+// DeleteBook moves a book to the trash, or with ?purge=true removes it for
+// good, which only administrators may do.
+func (bc *BookController) DeleteBook(c *gin.Context) {
 	bookID := c.Param("id")
 	objectID, err := bson.ObjectIDFromHex(bookID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid book ID"})
+		c.Error(badRequest("Invalid book ID"))
 		return
 	}
 
-	// First check if the book exists
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	var existingBook models.Book
-	err = bookCollection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&existingBook)
+	purge, err := strconv.ParseBool(c.DefaultQuery("purge", "false"))
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find book"})
-		}
+		c.Error(badRequest("purge must be true or false"))
 		return
 	}
+	if purge {
+		bc.purgeBook(c, objectID)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), bc.opts.Timeout)
+	defer cancel()
 
-	_, err = bookCollection.DeleteOne(ctx, bson.M{"_id": objectID})
+	before, deleted, err := bc.modifyBook(ctx, c, objectID, func(current models.Book) (models.Book, error) {
+		book, err := bc.store.Delete(ctx, objectID, current.Version)
+		if err != nil {
+			return models.Book{}, storeError("Failed to delete book", err)
+		}
+		return book, nil
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete book"})
+		c.Error(err)
+		return
+	}
+	if err := bc.recordHistory(ctx, c, models.ActionDelete, before, deleted); err != nil {
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Book deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Book moved to trash"})
 }
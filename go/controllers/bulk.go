@@ -0,0 +1,209 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"go-crud/models"
+	"go-crud/store"
+)
+
+// maxBulkOps bounds the number of operations in one bulk request.
+const maxBulkOps = 1000
+
+// bulkItem is one operation of a bulk request. Version, when given, makes
+// an update or delete conditional like If-Match does.
+type bulkItem struct {
+	Op      string       `json:"op"`
+	ID      string       `json:"id"`
+	Version *int64       `json:"version"`
+	Book    *models.Book `json:"book"`
+}
+
+// bulkItemResult is the outcome of one bulk operation: the book it wrote or
+// the problem that made it fail.
+type bulkItemResult struct {
+	Status int          `json:"status"`
+	ID     string       `json:"id,omitempty"`
+	Book   *models.Book `json:"book,omitempty"`
+	Error  *problem     `json:"error,omitempty"`
+}
+
+// BulkBooks runs a JSON array of create, update and delete operations as
+// one store bulk write and reports a result for each, in order. With
+// ?atomic=true either every operation is applied or none is.
+func (bc *BookController) BulkBooks(c *gin.Context) {
+	atomic, err := strconv.ParseBool(c.DefaultQuery("atomic", "false"))
+	if err != nil {
+		c.Error(badRequest("atomic must be true or false"))
+		return
+	}
+
+	var items []bulkItem
+	if err := json.NewDecoder(c.Request.Body).Decode(&items); err != nil {
+		c.Error(badRequest("Request body must be a JSON array of operations"))
+		return
+	}
+	if len(items) == 0 || len(items) > maxBulkOps {
+		c.Error(badRequest(fmt.Sprintf("A bulk request takes between 1 and %d operations", maxBulkOps)))
+		return
+	}
+
+	results := make([]bulkItemResult, len(items))
+	var ops []store.BulkOp
+	// opItems maps each store op to its item
+	var opItems []int
+	for i, item := range items {
+		op, err := bulkOp(item)
+		if err != nil {
+			results[i] = bulkFailure(c, err)
+			continue
+		}
+		ops = append(ops, op)
+		opItems = append(opItems, i)
+	}
+
+	if atomic && len(ops) < len(items) {
+		for i := range results {
+			if results[i].Error == nil {
+				results[i] = bulkFailure(c, rolledBack())
+			}
+		}
+		respondBulk(c, results, true)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), bc.opts.Timeout)
+	defer cancel()
+
+	var written []store.BulkResult
+	if len(ops) > 0 {
+		written, err = bc.store.BulkWrite(ctx, ops, atomic)
+		if errors.Is(err, store.ErrUnsupported) && atomic {
+			e := newAPIError(http.StatusNotImplemented, "Atomic bulk writes need the book database to support transactions")
+			e.Err = err
+			c.Error(e)
+			return
+		}
+		if err != nil {
+			c.Error(storeError("Failed to write books", err))
+			return
+		}
+	}
+
+	var history []models.HistoryEntry
+	for j, r := range written {
+		i := opItems[j]
+		if errors.Is(r.Err, store.ErrRolledBack) {
+			results[i] = bulkFailure(c, rolledBack())
+			continue
+		}
+		if r.Err != nil {
+			results[i] = bulkFailure(c, storeError("Failed to write book", r.Err))
+			continue
+		}
+
+		status, action := http.StatusOK, models.ActionUpdate
+		switch ops[j].Kind {
+		case store.BulkCreate:
+			status, action = http.StatusCreated, models.ActionCreate
+		case store.BulkDelete:
+			action = models.ActionDelete
+		}
+		book := r.Book
+		results[i] = bulkItemResult{Status: status, ID: book.ID.Hex(), Book: &book}
+		history = append(history, bc.historyEntry(c, action, r.Before, r.Book))
+	}
+	if len(history) > 0 {
+		if err := bc.addHistory(ctx, history...); err != nil {
+			c.Error(err)
+			return
+		}
+	}
+
+	respondBulk(c, results, atomic)
+}
+
+// bulkOp checks a bulk item and turns it into a store operation.
+func bulkOp(item bulkItem) (store.BulkOp, error) {
+	op := store.BulkOp{Kind: item.Op, IfVersion: store.AnyVersion}
+	switch item.Op {
+	case store.BulkCreate, store.BulkUpdate, store.BulkDelete:
+	default:
+		return op, badRequest("op must be create, update or delete")
+	}
+
+	if item.Op != store.BulkCreate {
+		id, err := bson.ObjectIDFromHex(item.ID)
+		if err != nil {
+			return op, badRequest("Invalid book ID")
+		}
+		op.ID = id
+	}
+	if item.Version != nil {
+		if *item.Version < 0 {
+			return op, badRequest("version must be a non-negative integer")
+		}
+		op.IfVersion = *item.Version
+	}
+
+	if item.Op != store.BulkDelete {
+		if item.Book == nil {
+			return op, badRequest("book is required for " + item.Op)
+		}
+		if err := binding.Validator.ValidateStruct(item.Book); err != nil {
+			return op, bindError(err)
+		}
+		op.Book = *item.Book
+	}
+	return op, nil
+}
+
+// rolledBack is the problem of an operation that was not applied because
+// another one in the same atomic request failed.
+func rolledBack() *APIError {
+	return newAPIError(http.StatusFailedDependency, "Not applied because another operation failed")
+}
+
+// bulkFailure reports a failed bulk operation, logging internal errors
+// like ErrorHandler does.
+func bulkFailure(c *gin.Context, err error) bulkItemResult {
+	apiErr := toAPIError(err)
+	if apiErr.Status >= http.StatusInternalServerError {
+		logError(c, "bulk operation failed", apiErr, "status", apiErr.Status)
+	}
+	return bulkItemResult{
+		Status: apiErr.Status,
+		Error: &problem{
+			Type:   apiErr.Type,
+			Title:  apiErr.Title,
+			Status: apiErr.Status,
+			Detail: apiErr.Detail,
+			Errors: apiErr.Errors,
+		},
+	}
+}
+
+// respondBulk sends the results of a bulk request. The request itself
+// succeeded even when operations failed, so the status is always 200.
+func respondBulk(c *gin.Context, results []bulkItemResult, atomic bool) {
+	failed := 0
+	for _, r := range results {
+		if r.Error != nil {
+			failed++
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"results":    results,
+		"failed":     failed,
+		"rolledBack": atomic && failed > 0,
+	})
+}
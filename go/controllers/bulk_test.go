@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestBulkBooksAtomicRollsBack(t *testing.T) {
+	router, s := newTestRouter(t, RouterOptions{})
+	dune := createBook(t, router, `{"title":"Dune","author":"Frank Herbert","year":1965}`)
+
+	// The delete expects the version the update replaces, so it fails
+	body := fmt.Sprintf(`[
+		{"op":"create","book":{"title":"Emma","author":"Jane Austen","year":1815}},
+		{"op":"update","id":%[1]q,"version":1,"book":{"title":"Dune","author":"Frank Herbert","year":1966}},
+		{"op":"delete","id":%[1]q,"version":1}
+	]`, dune.ID.Hex())
+	w := serve(router, http.MethodPost, "/books/_bulk?atomic=true", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+
+	var res struct {
+		Results    []bulkItemResult `json:"results"`
+		Failed     int              `json:"failed"`
+		RolledBack bool             `json:"rolledBack"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	want := []int{http.StatusFailedDependency, http.StatusFailedDependency, http.StatusPreconditionFailed}
+	for i, r := range res.Results {
+		if r.Status != want[i] || r.Book != nil {
+			t.Errorf("result %d = %+v, want a %d failure", i, r, want[i])
+		}
+	}
+	if res.Failed != 3 || !res.RolledBack {
+		t.Errorf("failed = %d, rolledBack = %t, want 3 and true", res.Failed, res.RolledBack)
+	}
+
+	if total := listTotal(t, router, "/books"); total != 1 {
+		t.Errorf("%d books after the rollback, want 1", total)
+	}
+	if got := decodeBook(t, serve(router, http.MethodGet, "/books/"+dune.ID.Hex(), ""), http.StatusOK); got.Year != 1965 || got.Version != 1 {
+		t.Errorf("book after the rollback = %+v, want it unchanged", got)
+	}
+	entries, err := s.History(context.Background(), dune.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("%d history entries, want only the create", len(entries))
+	}
+}
+
+func TestBulkBooksAtomicRejectsInvalidOps(t *testing.T) {
+	router, _ := newTestRouter(t, RouterOptions{})
+
+	body := `[
+		{"op":"create","book":{"title":"Emma","author":"Jane Austen","year":1815}},
+		{"op":"rename","id":"nope"}
+	]`
+	w := serve(router, http.MethodPost, "/books/_bulk?atomic=true", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if total := listTotal(t, router, "/books"); total != 0 {
+		t.Errorf("%d books after a rejected atomic request, want 0", total)
+	}
+}
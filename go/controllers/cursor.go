@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"go-crud/models"
+	"go-crud/store"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// pageCursor is the decoded form of the opaque cursor handed to clients.
+// It records the sort order it was issued for, so it cannot be replayed
+// against a different one, and the sort key of the last book returned.
+type pageCursor struct {
+	Sort string            `json:"s"`
+	Key  []json.RawMessage `json:"k"`
+}
+
+// encodeCursor returns the cursor that resumes listing after book.
+func encodeCursor(book models.Book, sortParam string, sortFields []store.SortField) string {
+	cur := pageCursor{Sort: sortParam}
+	for _, v := range store.SortKey(book, sortFields) {
+		raw, _ := json.Marshal(v)
+		cur.Key = append(cur.Key, raw)
+	}
+	data, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor turns a cursor back into a store sort key, checking that it
+// was issued for the same sort order.
+func decodeCursor(s, sortParam string, sortFields []store.SortField) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	var cur pageCursor
+	if err := json.Unmarshal(data, &cur); err != nil {
+		return nil, errInvalidCursor
+	}
+	if cur.Sort != sortParam {
+		return nil, fmt.Errorf("cursor was issued for a different sort order")
+	}
+
+	stable := store.StableSort(sortFields)
+	if len(cur.Key) != len(stable) {
+		return nil, errInvalidCursor
+	}
+
+	key := make([]any, len(stable))
+	for i, f := range stable {
+		v, err := decodeKeyValue(f.Field, cur.Key[i])
+		if err != nil {
+			return nil, errInvalidCursor
+		}
+		key[i] = v
+	}
+	return key, nil
+}
+
+// decodeKeyValue decodes one sort key value with the type of its field.
+func decodeKeyValue(field string, raw json.RawMessage) (any, error) {
+	kind := kindString
+	for _, lf := range listFields {
+		if lf.stored == field {
+			kind = lf.kind
+		}
+	}
+
+	switch kind {
+	case kindID:
+		var id bson.ObjectID
+		err := json.Unmarshal(raw, &id)
+		return id, err
+	case kindInt:
+		var n int
+		err := json.Unmarshal(raw, &n)
+		return n, err
+	case kindTime:
+		var t time.Time
+		err := json.Unmarshal(raw, &t)
+		return t.UTC(), err
+	}
+
+	var s string
+	err := json.Unmarshal(raw, &s)
+	return s, err
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"go-crud/models"
+	"go-crud/store"
+)
+
+func TestParseListQueryCursor(t *testing.T) {
+	q, _ := url.ParseQuery("cursor=&limit=5")
+	lq, err := parseListQuery(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !lq.useCursor || lq.opts.Limit != 6 || lq.opts.After != nil {
+		t.Errorf("first cursor page: useCursor = %t, store limit = %d, after = %v", lq.useCursor, lq.opts.Limit, lq.opts.After)
+	}
+
+	for query, want := range map[string]string{
+		"cursor=&page=2":   "cursor cannot be combined",
+		"cursor=&offset=0": "cursor cannot be combined",
+		"cursor=!!!":       "invalid cursor",
+	} {
+		q, _ := url.ParseQuery(query)
+		if _, err := parseListQuery(q); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("parseListQuery(%q) error = %v, want %q", query, err, want)
+		}
+	}
+}
+
+func TestNewCursorPage(t *testing.T) {
+	books := []models.Book{
+		{ID: bson.NewObjectID(), Title: "Dune"},
+		{ID: bson.NewObjectID(), Title: "Emma"},
+		{ID: bson.NewObjectID(), Title: "Hyperion"},
+	}
+	u, _ := url.Parse("/books?cursor=&limit=2&sort=title")
+	lq, err := parseListQuery(u.Query())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The store returns one book more than the limit when there is a next
+	// page
+	page := newCursorPage(u, lq, store.ListResult{Books: books, Total: 3})
+	if got := page.Data.([]models.Book); len(got) != 2 {
+		t.Fatalf("page has %d books, want 2", len(got))
+	}
+	next, _ := url.Parse(page.Links.Next)
+	if next.Query().Get("cursor") != page.NextCursor || next.Query().Get("sort") != "title" {
+		t.Errorf("next link %q does not resume with the cursor and sort", page.Links.Next)
+	}
+	after, err := decodeCursor(page.NextCursor, "title", lq.opts.Sort)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(after, []any{"Emma", books[1].ID}) {
+		t.Errorf("cursor resumes after %v, want Emma", after)
+	}
+
+	last := newCursorPage(u, lq, store.ListResult{Books: books[:2], Total: 2})
+	if last.NextCursor != "" || last.Links.Next != "" {
+		t.Errorf("last page has next cursor %q and link %q, want none", last.NextCursor, last.Links.Next)
+	}
+}
+
+func TestDecodeCursor(t *testing.T) {
+	book := models.Book{
+		ID:        bson.NewObjectID(),
+		Title:     "Dune",
+		Year:      1965,
+		CreatedAt: time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC),
+	}
+
+	tests := []struct {
+		sort   string
+		fields []store.SortField
+		want   []any
+	}{
+		{"", nil, []any{book.ID}},
+		{"title", []store.SortField{{Field: "title"}}, []any{"Dune", book.ID}},
+		{"-year", []store.SortField{{Field: "year", Desc: true}}, []any{1965, book.ID}},
+		{"createdAt", []store.SortField{{Field: "createdAt"}}, []any{book.CreatedAt, book.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			cursor := encodeCursor(book, tt.sort, tt.fields)
+			got, err := decodeCursor(cursor, tt.sort, tt.fields)
+			if err != nil {
+				t.Fatalf("decodeCursor: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeCursor = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorErrors(t *testing.T) {
+	book := models.Book{ID: bson.NewObjectID(), Title: "Dune"}
+	byTitle := []store.SortField{{Field: "title"}}
+	cursor := encodeCursor(book, "title", byTitle)
+
+	if _, err := decodeCursor(cursor, "-year", []store.SortField{{Field: "year", Desc: true}}); err == nil || !strings.Contains(err.Error(), "different sort order") {
+		t.Errorf("decodeCursor with another sort: error = %v, want a sort order mismatch", err)
+	}
+
+	for _, s := range []string{"not base64!", "bm90IGpzb24", "eyJzIjoidGl0bGUiLCJrIjpbXX0", "eyJzIjoidGl0bGUiLCJrIjpbMSwyXX0"} {
+		if _, err := decodeCursor(s, "title", byTitle); !errors.Is(err, errInvalidCursor) {
+			t.Errorf("decodeCursor(%q) error = %v, want %v", s, err, errInvalidCursor)
+		}
+	}
+}
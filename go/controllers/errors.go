@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-crud/store"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem types with a meaning more specific than their HTTP status. Other
+// problems use "about:blank" as RFC 7807 recommends.
+const (
+	problemValidation     = "/problems/validation-error"
+	problemInvalidPatch   = "/problems/invalid-patch"
+	problemPatchTestFails = "/problems/patch-test-failed"
+)
+
+// retryAfterUnavailable is the Retry-After hint sent while the book store
+// is unreachable.
+const retryAfterUnavailable = 5 * time.Second
+
+// statusClientClosedRequest is the nonstandard status, taken from nginx,
+// of requests whose client went away before the response was ready.
+const statusClientClosedRequest = 499
+
+// APIError is an error that the API reports to clients as an RFC 7807
+// problem. Err is the internal cause: it is logged, never sent.
+// RetryAfter, when set, is sent as a Retry-After header.
+type APIError struct {
+	Status     int
+	Type       string
+	Title      string
+	Detail     string
+	Errors     []fieldError
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *APIError) Error() string {
+	if e.Err != nil {
+		return e.Detail + ": " + e.Err.Error()
+	}
+	return e.Detail
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// newAPIError returns a problem of the given status with the standard
+// title for it.
+func newAPIError(status int, detail string) *APIError {
+	return &APIError{Status: status, Type: "about:blank", Title: http.StatusText(status), Detail: detail}
+}
+
+func badRequest(detail string) *APIError {
+	return newAPIError(http.StatusBadRequest, detail)
+}
+
+// internalError hides err behind detail, which should say what failed
+// without exposing how.
+func internalError(detail string, err error) *APIError {
+	e := newAPIError(http.StatusInternalServerError, detail)
+	e.Err = err
+	return e
+}
+
+func validationError(errs []fieldError) *APIError {
+	e := newAPIError(http.StatusUnprocessableEntity, "One or more fields are invalid")
+	e.Type = problemValidation
+	e.Title = "Validation Failed"
+	e.Errors = errs
+	return e
+}
+
+// storeError wraps an error from a store call: the errors every BookStore
+// may return keep their own status and anything else is an internal error
+// described by detail.
+func storeError(detail string, err error) error {
+	if apiErr := storeAPIError(err); apiErr != nil {
+		return apiErr
+	}
+	return internalError(detail, err)
+}
+
+// storeAPIError maps the store package errors to problems, or returns nil
+// for errors it does not know.
+func storeAPIError(err error) *APIError {
+	var apiErr *APIError
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		apiErr = newAPIError(http.StatusNotFound, "Book not found")
+	case errors.Is(err, store.ErrVersionMismatch):
+		apiErr = preconditionFailed()
+	case errors.Is(err, store.ErrConflict):
+		apiErr = newAPIError(http.StatusConflict, "Book conflicts with an existing book")
+	case errors.Is(err, context.Canceled):
+		apiErr = newAPIError(statusClientClosedRequest, "The request was canceled")
+		apiErr.Title = "Client Closed Request"
+	case errors.Is(err, store.ErrUnavailable):
+		apiErr = newAPIError(http.StatusServiceUnavailable, "The book database is unavailable, try again later")
+		apiErr.RetryAfter = retryAfterUnavailable
+	case errors.Is(err, store.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		apiErr = newAPIError(http.StatusGatewayTimeout, "The book database did not respond in time")
+	case errors.Is(err, store.ErrUnsupported):
+		apiErr = newAPIError(http.StatusNotImplemented, "The book database does not support this operation")
+	default:
+		return nil
+	}
+	apiErr.Err = err
+	return apiErr
+}
+
+// problem is the application/problem+json response body.
+type problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []fieldError `json:"errors,omitempty"`
+}
+
+// toAPIError maps an error raised by a handler to the problem reported for
+// it. Errors the API does not know are internal.
+func toAPIError(err error) *APIError {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	if apiErr := storeAPIError(err); apiErr != nil {
+		return apiErr
+	}
+	if errs, ok := fieldErrors(err); ok {
+		return validationError(errs)
+	}
+	return internalError("An internal error occurred", err)
+}
+
+// logError logs err, a failure while serving c, with the request's method,
+// path and request ID and any further attributes in args.
+func logError(c *gin.Context, msg string, err error, args ...any) {
+	args = append([]any{"method", c.Request.Method, "path", c.Request.URL.Path, "error", err}, args...)
+	slog.ErrorContext(c.Request.Context(), msg, args...)
+}
+
+// ErrorHandler renders the last error added with c.Error as a problem
+// response, unless the handler already wrote one.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		apiErr := toAPIError(c.Errors.Last().Err)
+		if apiErr.Status >= http.StatusInternalServerError {
+			logError(c, "request failed", apiErr, "status", apiErr.Status)
+		}
+
+		if apiErr.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(apiErr.RetryAfter.Seconds())))
+		}
+		c.Header("Content-Type", problemContentType)
+		c.JSON(apiErr.Status, problem{
+			Type:     apiErr.Type,
+			Title:    apiErr.Title,
+			Status:   apiErr.Status,
+			Detail:   apiErr.Detail,
+			Instance: c.Request.URL.Path,
+			Errors:   apiErr.Errors,
+		})
+	}
+}
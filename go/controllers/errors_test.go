@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"go-crud/store"
+)
+
+func TestErrorHandlerMapsStoreErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		err        error
+		status     int
+		retryAfter string
+	}{
+		{"duplicate key", fmt.Errorf("%w: E11000 duplicate key error", store.ErrConflict), http.StatusConflict, ""},
+		{"server selection", fmt.Errorf("%w: server selection error", store.ErrUnavailable), http.StatusServiceUnavailable, "5"},
+		{"store timeout", store.ErrTimeout, http.StatusGatewayTimeout, ""},
+		{"deadline", context.DeadlineExceeded, http.StatusGatewayTimeout, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(ErrorHandler())
+			router.GET("/books", func(c *gin.Context) {
+				c.Error(tt.err)
+			})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/books", nil))
+
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			if got := w.Header().Get("Retry-After"); got != tt.retryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.retryAfter)
+			}
+			if got := w.Header().Get("Content-Type"); got != problemContentType {
+				t.Errorf("Content-Type = %q, want %q", got, problemContentType)
+			}
+
+			var p problem
+			if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+				t.Fatalf("decoding problem: %v", err)
+			}
+			if p.Status != tt.status || p.Instance != "/books" {
+				t.Errorf("problem = %+v, want status %d for /books", p, tt.status)
+			}
+		})
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		ptype  string
+		title  string
+	}{
+		{"bad request", badRequest("limit must be between 1 and 100"), http.StatusBadRequest, "about:blank", "Bad Request"},
+		{"not found", store.ErrNotFound, http.StatusNotFound, "about:blank", "Not Found"},
+		{"version mismatch", store.ErrVersionMismatch, http.StatusPreconditionFailed, "about:blank", "Precondition Failed"},
+		{"canceled", context.Canceled, statusClientClosedRequest, "about:blank", "Client Closed Request"},
+		{"unsupported", store.ErrUnsupported, http.StatusNotImplemented, "about:blank", "Not Implemented"},
+		{"validation", validationError([]fieldError{{Field: "title", Rule: "required"}}), http.StatusUnprocessableEntity, problemValidation, "Validation Failed"},
+		{"unknown", errors.New("connection string has the password"), http.StatusInternalServerError, "about:blank", "Internal Server Error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(ErrorHandler())
+			router.GET("/books", func(c *gin.Context) {
+				c.Error(tt.err)
+			})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/books", nil))
+
+			if got := w.Header().Get("Content-Type"); got != problemContentType {
+				t.Errorf("Content-Type = %q, want %q", got, problemContentType)
+			}
+			var p problem
+			if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+				t.Fatalf("decoding problem: %v", err)
+			}
+			if w.Code != tt.status || p.Status != tt.status || p.Type != tt.ptype || p.Title != tt.title || p.Instance != "/books" {
+				t.Errorf("status %d, problem %+v, want %d %s %q for /books", w.Code, p, tt.status, tt.ptype, tt.title)
+			}
+			if strings.Contains(w.Body.String(), "password") {
+				t.Errorf("problem %s exposes the internal error", w.Body)
+			}
+		})
+	}
+}
+
+func TestErrorHandlerKeepsWrittenResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ErrorHandler())
+	router.GET("/books", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"data": []string{}})
+		c.Error(errors.New("late failure"))
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/books", nil))
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "status") {
+		t.Errorf("status %d, body %s, want the handler's response", w.Code, w.Body)
+	}
+}
+
+func TestRouterProblems(t *testing.T) {
+	router, _ := newTestRouter(t, RouterOptions{})
+	router.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	tests := []struct {
+		method, path string
+		status       int
+	}{
+		{http.MethodGet, "/nope", http.StatusNotFound},
+		{http.MethodDelete, "/healthz", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/panic", http.StatusInternalServerError},
+		{http.MethodGet, "/books/not-an-id", http.StatusBadRequest},
+		{http.MethodGet, "/books/000000000000000000000001", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			w := serve(router, tt.method, tt.path, "")
+			if w.Code != tt.status || w.Header().Get("Content-Type") != problemContentType {
+				t.Errorf("status %d, Content-Type %q, want a %d problem", w.Code, w.Header().Get("Content-Type"), tt.status)
+			}
+			if tt.status == http.StatusMethodNotAllowed && w.Header().Get("Allow") == "" {
+				t.Error("405 without an Allow header")
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"go-crud/models"
+	"go-crud/store"
+)
+
+// contentETag is a strong entity tag for a JSON representation.
+func contentETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// bookETag is the strong entity tag of a book, the same one GET /books/:id
+// sends for it.
+func bookETag(book models.Book) string {
+	body, _ := json.Marshal(book)
+	return contentETag(body)
+}
+
+// etagMatches reports whether an If-Match header value lists etag, using
+// the strong comparison RFC 9110 requires for If-Match.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// noneMatch reports whether an If-None-Match header value lists etag,
+// using the weak comparison RFC 9110 requires for If-None-Match.
+func noneMatch(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified evaluates If-None-Match, or If-Modified-Since when there is
+// no If-None-Match, against a representation's validators.
+func notModified(c *gin.Context, etag string, lastModified time.Time) bool {
+	if header := c.GetHeader("If-None-Match"); header != "" {
+		return noneMatch(header, etag)
+	}
+	if header := c.GetHeader("If-Modified-Since"); header != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(header)
+		return err == nil && !lastModified.Truncate(time.Second).After(since)
+	}
+	return false
+}
+
+// respondConditional sends body as JSON with an ETag computed from it and
+// a Last-Modified of lastModified, unless it is zero. When the client's
+// copy is still current it gets 304 Not Modified with no body instead.
+func respondConditional(c *gin.Context, body any, lastModified time.Time) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		c.Error(internalError("Failed to encode response", err))
+		return
+	}
+	respondTagged(c, data, contentETag(data), lastModified)
+}
+
+// respondTagged is respondConditional for a body already encoded, sent
+// with the given ETag.
+func respondTagged(c *gin.Context, data []byte, etag string, lastModified time.Time) {
+	c.Header("ETag", etag)
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(c, etag, lastModified) {
+		c.Status(http.StatusNotModified)
+		c.Writer.WriteHeaderNow()
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+}
+
+func preconditionFailed() *APIError {
+	return newAPIError(http.StatusPreconditionFailed, "Book has been modified since it was read")
+}
+
+// maxWriteAttempts bounds how often modifyBook retries a write that lost a
+// race with another one.
+const maxWriteAttempts = 3
+
+// modifyBook reads a book, checks the request's If-Match against it and
+// calls write with it. write must condition its store call on the version
+// it is given, so that the book returned as before is exactly what was
+// changed. Without If-Match a write that finds the book at another version
+// is retried on the new one.
+//
+// write returns errors ready to report, such as storeError's.
+func (bc *BookController) modifyBook(ctx context.Context, c *gin.Context, id bson.ObjectID,
+	write func(current models.Book) (models.Book, error)) (before, after models.Book, err error) {
+	header := c.GetHeader("If-Match")
+	for attempt := 1; ; attempt++ {
+		current, err := bc.store.Get(ctx, id)
+		if err != nil {
+			return models.Book{}, models.Book{}, storeError("Failed to find book", err)
+		}
+		if header != "" && !etagMatches(header, bookETag(current)) {
+			return models.Book{}, models.Book{}, preconditionFailed()
+		}
+
+		after, err := write(current)
+		if errors.Is(err, store.ErrVersionMismatch) && header == "" && attempt < maxWriteAttempts {
+			continue
+		}
+		if err != nil {
+			return models.Book{}, models.Book{}, err
+		}
+		return current, after, nil
+	}
+}
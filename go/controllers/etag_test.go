@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestConditionalGetBook(t *testing.T) {
+	router, _ := newTestRouter(t, RouterOptions{})
+	book := createBook(t, router, `{"title": "Dune", "author": "Frank Herbert", "year": 1965}`)
+	path := "/books/" + book.ID.Hex()
+
+	w := serve(router, http.MethodGet, path, "")
+	etag, lastModified := w.Header().Get("ETag"), w.Header().Get("Last-Modified")
+	if etag != bookETag(book) || lastModified == "" {
+		t.Fatalf("ETag %q, Last-Modified %q, want the book's ETag and a Last-Modified", etag, lastModified)
+	}
+
+	tests := []struct {
+		name    string
+		headers []string
+		status  int
+	}{
+		{"matching etag", []string{"If-None-Match", etag}, http.StatusNotModified},
+		{"weak etag", []string{"If-None-Match", "W/" + etag}, http.StatusNotModified},
+		{"other etag", []string{"If-None-Match", `"other"`}, http.StatusOK},
+		{"not modified since", []string{"If-Modified-Since", lastModified}, http.StatusNotModified},
+		{"modified since", []string{"If-Modified-Since", time.Unix(0, 0).UTC().Format(http.TimeFormat)}, http.StatusOK},
+		// If-None-Match wins over If-Modified-Since
+		{"other etag, not modified since", []string{"If-None-Match", `"other"`, "If-Modified-Since", lastModified}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(router, http.MethodGet, path, "", tt.headers...)
+			if w.Code != tt.status {
+				t.Errorf("status %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestConditionalListBooks(t *testing.T) {
+	router, _ := newTestRouter(t, RouterOptions{})
+	for _, title := range []string{"Anathem", "Beloved", "Carrie", "Dune", "Emma", "Frankenstein"} {
+		createBook(t, router, `{"title": "`+title+`", "author": "Someone", "year": 1990}`)
+	}
+
+	const path = "/books?limit=2&sort=title"
+	w := serve(router, http.MethodGet, path, "")
+	etag := w.Header().Get("ETag")
+	if etag == "" || w.Header().Get("Last-Modified") != "" {
+		t.Fatalf("ETag %q, Last-Modified %q, want only an ETag", etag, w.Header().Get("Last-Modified"))
+	}
+	if w := serve(router, http.MethodGet, path, "", "If-None-Match", etag); w.Code != http.StatusNotModified {
+		t.Errorf("unchanged page: status %d, want 304", w.Code)
+	}
+
+	// A book on a later page changes the total but no book on this page
+	createBook(t, router, `{"title": "Zazie", "author": "Someone", "year": 1990}`)
+	since := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if w := serve(router, http.MethodGet, path, "", "If-Modified-Since", since); w.Code != http.StatusOK {
+		t.Errorf("If-Modified-Since after a create: status %d, want 200", w.Code)
+	}
+	if w := serve(router, http.MethodGet, path, "", "If-None-Match", etag); w.Code != http.StatusOK {
+		t.Errorf("If-None-Match after a create: status %d, want 200", w.Code)
+	}
+}
+
+func TestConditionalBatchGet(t *testing.T) {
+	router, _ := newTestRouter(t, RouterOptions{})
+	dune := createBook(t, router, `{"title": "Dune", "author": "Frank Herbert", "year": 1965}`)
+	emma := createBook(t, router, `{"title": "Emma", "author": "Jane Austen", "year": 1815}`)
+
+	path := "/books?ids=" + dune.ID.Hex() + "," + emma.ID.Hex()
+	w := serve(router, http.MethodGet, path, "")
+	etag := w.Header().Get("ETag")
+	if etag == "" || w.Header().Get("Last-Modified") != "" {
+		t.Fatalf("ETag %q, Last-Modified %q, want only an ETag", etag, w.Header().Get("Last-Modified"))
+	}
+	if w := serve(router, http.MethodGet, path, "", "If-None-Match", etag); w.Code != http.StatusNotModified {
+		t.Errorf("unchanged books: status %d, want 304", w.Code)
+	}
+
+	// POST is not a read a 304 could answer
+	body := `{"ids": ["` + dune.ID.Hex() + `", "` + emma.ID.Hex() + `"]}`
+	w = serve(router, http.MethodPost, "/books/_mget", body, "If-None-Match", etag)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != "" {
+		t.Errorf("POST /books/_mget with If-None-Match: status %d, ETag %q, want 200 without an ETag", w.Code, w.Header().Get("ETag"))
+	}
+
+	// Deleting Emma moves it to missing, leaving only Dune's older updatedAt
+	if w := serve(router, http.MethodDelete, "/books/"+emma.ID.Hex(), ""); w.Code != http.StatusOK {
+		t.Fatalf("DELETE: status %d", w.Code)
+	}
+	since := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if w := serve(router, http.MethodGet, path, "", "If-Modified-Since", since); w.Code != http.StatusOK {
+		t.Errorf("If-Modified-Since after a delete: status %d, want 200", w.Code)
+	}
+	if w := serve(router, http.MethodGet, path, "", "If-None-Match", etag); w.Code != http.StatusOK {
+		t.Errorf("If-None-Match after a delete: status %d, want 200", w.Code)
+	}
+}
+
+func TestProjectedGetBookETag(t *testing.T) {
+	router, _ := newTestRouter(t, RouterOptions{})
+	book := createBook(t, router, `{"title": "Dune", "author": "Frank Herbert", "year": 1965}`)
+	path := "/books/" + book.ID.Hex()
+
+	w := serve(router, http.MethodGet, path+"?fields=title", "")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag != bookETag(book) {
+		t.Fatalf("status %d, ETag %q, want the book's ETag %q", w.Code, etag, bookETag(book))
+	}
+	if w := serve(router, http.MethodGet, path+"?fields=title", "", "If-None-Match", etag); w.Code != http.StatusNotModified {
+		t.Errorf("unchanged projection: status %d, want 304", w.Code)
+	}
+
+	w = serve(router, http.MethodPut, path, `{"title": "Dune Messiah", "author": "Frank Herbert", "year": 1969}`, "If-Match", etag)
+	if w.Code != http.StatusOK {
+		t.Errorf("If-Match with a projection's ETag: status %d, want 200", w.Code)
+	}
+	w = serve(router, http.MethodPut, path, `{"title": "Children of Dune", "author": "Frank Herbert", "year": 1976}`, "If-Match", etag)
+	if w.Code != http.StatusPreconditionFailed {
+		t.Errorf("If-Match with a stale projection's ETag: status %d, want 412", w.Code)
+	}
+}
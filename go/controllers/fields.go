@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go-crud/models"
+)
+
+// projectableFields maps the JSON names accepted by ?fields= to stored
+// fields. Only these can be asked for; id is always sent.
+var projectableFields = map[string]string{
+	"id":        "_id",
+	"title":     "title",
+	"author":    "author",
+	"year":      "year",
+	"version":   "version",
+	"createdAt": "createdAt",
+	"updatedAt": "updatedAt",
+	"deletedAt": "deletedAt",
+}
+
+// parseFields reads a ?fields= value into JSON field names. An empty value
+// means every field and returns nil.
+func parseFields(v string) ([]string, error) {
+	if v == "" {
+		return nil, nil
+	}
+
+	names := []string{}
+	for _, name := range strings.Split(v, ",") {
+		name = strings.TrimSpace(name)
+		if _, ok := projectableFields[name]; !ok {
+			return nil, fmt.Errorf("unknown field %q in fields", name)
+		}
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// storedFields returns the stored fields to load for the JSON fields names
+// plus the stored fields in extra, or nil to load every field.
+func storedFields(names []string, extra ...string) []string {
+	if names == nil {
+		return nil
+	}
+
+	var fields []string
+	add := func(f string) {
+		if !slices.Contains(fields, f) {
+			fields = append(fields, f)
+		}
+	}
+	for _, name := range names {
+		add(projectableFields[name])
+	}
+	for _, f := range extra {
+		add(f)
+	}
+	return fields
+}
+
+// projectBook returns the JSON fields names of book, with its id, or the
+// whole book when names is nil.
+func projectBook(book models.Book, names []string) any {
+	if names == nil {
+		return book
+	}
+
+	data, _ := json.Marshal(book)
+	var all map[string]json.RawMessage
+	json.Unmarshal(data, &all)
+
+	out := map[string]json.RawMessage{"id": all["id"]}
+	for _, name := range names {
+		if v, ok := all[name]; ok {
+			out[name] = v
+		}
+	}
+	return out
+}
+
+func projectBooks(books []models.Book, names []string) any {
+	if names == nil {
+		return books
+	}
+
+	out := make([]any, len(books))
+	for i, book := range books {
+		out[i] = projectBook(book, names)
+	}
+	return out
+}
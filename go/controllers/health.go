@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-crud/store"
+)
+
+// Detail levels of the /readyz response.
+const (
+	// HealthDetailNone sends only the overall status.
+	HealthDetailNone = "none"
+	// HealthDetailStatus adds the status and latency of each component.
+	HealthDetailStatus = "status"
+	// HealthDetailFull also says why a component is down, which can
+	// expose internal addresses.
+	HealthDetailFull = "full"
+)
+
+// defaultHealthTimeout bounds each /readyz check when RouterOptions does
+// not.
+const defaultHealthTimeout = 2 * time.Second
+
+// errNotConnected is reported for the book store until its first
+// connection succeeds.
+var errNotConnected = errors.New("not connected yet")
+
+// HealthCheck is a dependency checked by /readyz.
+type HealthCheck struct {
+	// Name identifies the component in the /readyz response.
+	Name string
+	// Check returns an error when the dependency cannot be used. It
+	// should give up once ctx is done.
+	Check func(ctx context.Context) error
+}
+
+// storeHealthCheck checks that the book store finished connecting, as
+// reported by ready, and still answers.
+func storeHealthCheck(s store.BookStore, ready func() bool) HealthCheck {
+	return HealthCheck{
+		Name: "database",
+		Check: func(ctx context.Context) error {
+			if ready != nil && !ready() {
+				return errNotConnected
+			}
+			return s.Ping(ctx)
+		},
+	}
+}
+
+// componentHealth is the /readyz status of one HealthCheck.
+type componentHealth struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// requireReady answers with 503 until the store is ready, as when the
+// server started before the database was reachable.
+func (bc *BookController) requireReady() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if bc.opts.Ready != nil && !bc.opts.Ready() {
+			c.Error(storeError("Book store is not ready", store.ErrUnavailable))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// liveHandler serves GET /healthz, which only says that the process
+// serves HTTP. It checks no dependency, so that an orchestrator does not
+// restart the server over a database outage.
+func liveHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// readyHandler serves GET /readyz, which runs checks in parallel, each
+// bounded by timeout, and reports ready only when all pass so that load
+// balancers route to the server once it can serve the books API. detail
+// is one of the HealthDetail levels.
+func readyHandler(checks []HealthCheck, timeout time.Duration, detail string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		results := make([]componentHealth, len(checks))
+		var wg sync.WaitGroup
+		for i, check := range checks {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				start := time.Now()
+				err := check.Check(ctx)
+				results[i] = componentHealth{
+					Status:    "up",
+					LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+				}
+				if err != nil {
+					results[i].Status = "down"
+					results[i].Error = err.Error()
+				}
+			}()
+		}
+		wg.Wait()
+
+		status, code := "ready", http.StatusOK
+		components := make(map[string]componentHealth, len(results))
+		for i, res := range results {
+			if res.Status != "up" {
+				status, code = "not ready", http.StatusServiceUnavailable
+			}
+			if detail != HealthDetailFull {
+				res.Error = ""
+			}
+			components[checks[i].Name] = res
+		}
+
+		body := gin.H{"status": status}
+		if detail != HealthDetailNone {
+			body["components"] = components
+		}
+		c.Header("Cache-Control", "no-store")
+		c.JSON(code, body)
+	}
+}
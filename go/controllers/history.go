@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"go-crud/models"
+)
+
+// maxActorLength bounds the X-Actor header recorded in the history.
+const maxActorLength = 100
+
+// auditedFields are the book fields, by JSON name, whose changes the
+// history records. IDs, versions and write timestamps are left out as
+// every entry carries them.
+var auditedFields = []string{"title", "author", "year", "deletedAt"}
+
+// auditedValues returns the audited fields of book, with nil for fields it
+// does not have. A book with no ID has none.
+func auditedValues(book models.Book) map[string]any {
+	values := make(map[string]any, len(auditedFields))
+	if book.ID.IsZero() {
+		return values
+	}
+	values["title"] = book.Title
+	values["author"] = book.Author
+	values["year"] = book.Year
+	if !book.DeletedAt.IsZero() {
+		values["deletedAt"] = book.DeletedAt
+	}
+	return values
+}
+
+// diffBooks lists the audited fields that differ between before and after.
+func diffBooks(before, after models.Book) []models.FieldChange {
+	b, a := auditedValues(before), auditedValues(after)
+	changes := []models.FieldChange{}
+	for _, field := range auditedFields {
+		if b[field] != a[field] {
+			changes = append(changes, models.FieldChange{Field: field, Before: b[field], After: a[field]})
+		}
+	}
+	return changes
+}
+
+// actor names who made a request: "admin" for requests with the admin
+// token, otherwise the X-Actor header the client sends, or "anonymous".
+func (bc *BookController) actor(c *gin.Context) string {
+	if bc.isAdmin(c) {
+		return "admin"
+	}
+	actor := strings.TrimSpace(c.GetHeader("X-Actor"))
+	if actor == "" {
+		return "anonymous"
+	}
+	if len(actor) > maxActorLength {
+		actor = actor[:maxActorLength]
+	}
+	return actor
+}
+
+// recordHistory adds the history entry for a change that has been written.
+// A book that no longer exists is passed as a zero after, one that did not
+// exist yet as a zero before.
+func (bc *BookController) recordHistory(ctx context.Context, c *gin.Context, action string, before, after models.Book) error {
+	return bc.addHistory(ctx, bc.historyEntry(c, action, before, after))
+}
+
+func (bc *BookController) historyEntry(c *gin.Context, action string, before, after models.Book) models.HistoryEntry {
+	entry := models.HistoryEntry{
+		BookID:    after.ID,
+		Action:    action,
+		Actor:     bc.actor(c),
+		Timestamp: after.UpdatedAt,
+		Version:   after.Version,
+		Changes:   diffBooks(before, after),
+		Book:      after,
+	}
+	if after.ID.IsZero() {
+		entry.BookID = before.ID
+		entry.Timestamp = time.Now().UTC().Truncate(time.Millisecond)
+		entry.Version = before.Version
+	}
+	return entry
+}
+
+// addHistory stores history entries. The changes they record have already
+// been made, but as_of and revert read books back from the history, so a
+// failure is reported to the client instead of leaving a revision missing
+// unnoticed.
+func (bc *BookController) addHistory(ctx context.Context, entries ...models.HistoryEntry) error {
+	// Use a fresh deadline that the client going away does not cut short
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), bc.opts.Timeout)
+	defer cancel()
+
+	if err := bc.store.AddHistory(ctx, entries...); err != nil {
+		return internalError("The change was saved but its revision could not be recorded", err)
+	}
+	return nil
+}
+
+// GetHistory lists the changes made to a book, oldest first. The history
+// outlives the book, so it is still available after a purge.
+func (bc *BookController) GetHistory(c *gin.Context) {
+	objectID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.Error(badRequest("Invalid book ID"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), bc.opts.Timeout)
+	defer cancel()
+
+	entries, err := bc.store.History(ctx, objectID)
+	if err != nil {
+		c.Error(storeError("Failed to fetch book history", err))
+		return
+	}
+
+	// Books stored before the history was kept have none, which is not
+	// the same as not existing
+	if len(entries) == 0 {
+		if _, err := bc.store.Get(ctx, objectID); err != nil {
+			c.Error(storeError("Failed to find book", err))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": entries})
+}
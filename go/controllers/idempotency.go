@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-crud/store"
+)
+
+const (
+	// maxIdempotencyKeyLength bounds the Idempotency-Key header.
+	maxIdempotencyKeyLength = 255
+	// problemIdempotencyMismatch is reported when a key is reused for a
+	// different request.
+	problemIdempotencyMismatch = "/problems/idempotency-key-reused"
+)
+
+// replayedHeaders are the response headers kept with a response to an
+// idempotent request.
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// recordingWriter keeps a copy of the response body it writes.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// idempotent makes the handlers after it honor an Idempotency-Key header.
+// The first request with a key claims it and, if it succeeds, its response
+// is kept for IdempotencyTTL and replayed for repeats with the same method,
+// path and body. Reusing a key for a different request is rejected with
+// 422. Failed requests are not kept, so they can be retried with the same
+// key.
+func (bc *BookController) idempotent() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.Error(badRequest("Idempotency-Key is too long"))
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.Error(badRequest("Failed to read request body"))
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), bc.opts.Timeout)
+		defer cancel()
+
+		fingerprint := requestFingerprint(c.Request, body)
+		rec, reserved, err := bc.store.ReserveIdempotencyKey(ctx, key, fingerprint, time.Now().Add(bc.opts.IdempotencyTTL))
+		if err != nil {
+			c.Error(storeError("Failed to check Idempotency-Key", err))
+			c.Abort()
+			return
+		}
+		if !reserved {
+			replayResponse(c, rec, fingerprint)
+			c.Abort()
+			return
+		}
+
+		w := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		// Settle the key even if the client has gone away, or its retries
+		// would find the key in progress until it expires
+		ctx, cancel = context.WithTimeout(context.WithoutCancel(c.Request.Context()), bc.opts.Timeout)
+		defer cancel()
+
+		// Errors are rendered by ErrorHandler after this returns, so a
+		// request that failed has not written anything yet
+		if len(c.Errors) > 0 || !w.Written() || w.Status() >= http.StatusInternalServerError {
+			if err := bc.store.ReleaseIdempotencyKey(ctx, key); err != nil {
+				logError(c, "failed to release Idempotency-Key", err)
+			}
+			return
+		}
+
+		response := store.StoredResponse{
+			Status: w.Status(),
+			Header: make(map[string]string),
+			Body:   w.body.Bytes(),
+		}
+		for _, name := range replayedHeaders {
+			if v := w.Header().Get(name); v != "" {
+				response.Header[name] = v
+			}
+		}
+		if err := bc.store.CompleteIdempotencyKey(ctx, key, response); err != nil {
+			logError(c, "failed to store response for Idempotency-Key", err)
+		}
+	}
+}
+
+// requestFingerprint identifies a request by method, path and body, so
+// that a key reused for another request can be told apart from a repeat.
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// replayResponse answers a request whose key was already claimed: with the
+// stored response for a repeat, or with an error when the key was used for
+// another request or that request is still in progress.
+func replayResponse(c *gin.Context, rec store.IdempotencyRecord, fingerprint string) {
+	if rec.Fingerprint != fingerprint {
+		e := newAPIError(http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request")
+		e.Type = problemIdempotencyMismatch
+		c.Error(e)
+		return
+	}
+	if rec.Response == nil {
+		e := newAPIError(http.StatusConflict, "A request with this Idempotency-Key is still in progress")
+		e.RetryAfter = time.Second
+		c.Error(e)
+		return
+	}
+
+	for name, v := range rec.Response.Header {
+		c.Header(name, v)
+	}
+	c.Header("Idempotent-Replayed", "true")
+	c.Data(rec.Response.Status, rec.Response.Header["Content-Type"], rec.Response.Body)
+}
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"go-crud/models"
+	"go-crud/store"
+)
+
+// createFailingStore fails Create while fail is set.
+type createFailingStore struct {
+	*store.MemoryStore
+	fail bool
+}
+
+func (s *createFailingStore) Create(ctx context.Context, book models.Book) (models.Book, error) {
+	if s.fail {
+		return models.Book{}, store.ErrUnavailable
+	}
+	return s.MemoryStore.Create(ctx, book)
+}
+
+func TestIdempotentCreateReplays(t *testing.T) {
+	router, _ := newTestRouter(t, RouterOptions{})
+	body := `{"title":"Dune","author":"Frank Herbert","year":1965}`
+
+	first := serve(router, http.MethodPost, "/books", body, "Idempotency-Key", "k1")
+	if first.Code != http.StatusCreated {
+		t.Fatalf("status %d: %s", first.Code, first.Body)
+	}
+	again := serve(router, http.MethodPost, "/books", body, "Idempotency-Key", "k1")
+	if again.Code != http.StatusCreated || again.Body.String() != first.Body.String() {
+		t.Errorf("repeat: status %d, body %s, want the first response", again.Code, again.Body)
+	}
+	if again.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("repeat without Idempotent-Replayed")
+	}
+	for _, name := range []string{"Location", "ETag"} {
+		if got, want := again.Header().Get(name), first.Header().Get(name); got != want {
+			t.Errorf("repeat %s = %q, want %q", name, got, want)
+		}
+	}
+	if total := listTotal(t, router, "/books"); total != 1 {
+		t.Errorf("%d books, want 1", total)
+	}
+}
+
+func TestIdempotentCreateRejectsReusedKey(t *testing.T) {
+	router, _ := newTestRouter(t, RouterOptions{})
+	serve(router, http.MethodPost, "/books", `{"title":"Dune","author":"Frank Herbert","year":1965}`, "Idempotency-Key", "k1")
+
+	w := serve(router, http.MethodPost, "/books", `{"title":"Emma","author":"Jane Austen","year":1815}`, "Idempotency-Key", "k1")
+	if w.Code != http.StatusUnprocessableEntity || w.Header().Get("Content-Type") != problemContentType {
+		t.Errorf("reused key: status %d, Content-Type %q, want a 422 problem", w.Code, w.Header().Get("Content-Type"))
+	}
+	if total := listTotal(t, router, "/books"); total != 1 {
+		t.Errorf("%d books, want 1", total)
+	}
+}
+
+func TestIdempotentCreateReleasesFailedKeys(t *testing.T) {
+	s := &createFailingStore{MemoryStore: store.NewMemoryStore()}
+	router := NewRouter(RouterOptions{Store: s})
+	body := `{"title":"Dune","author":"Frank Herbert","year":1965}`
+
+	// A client error releases the key, so it can be sent with a fixed body
+	w := serve(router, http.MethodPost, "/books", `{"title":"Dune"}`, "Idempotency-Key", "k1")
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("invalid book: status %d, want 422", w.Code)
+	}
+	s.fail = true
+	w = serve(router, http.MethodPost, "/books", body, "Idempotency-Key", "k1")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("failing store: status %d, want 503", w.Code)
+	}
+	s.fail = false
+
+	w = serve(router, http.MethodPost, "/books", body, "Idempotency-Key", "k1")
+	if w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("retry: status %d, Idempotent-Replayed %q, want a new 201", w.Code, w.Header().Get("Idempotent-Replayed"))
+	}
+}
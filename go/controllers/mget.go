@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"go-crud/models"
+)
+
+// maxBatchIDs bounds the IDs of one batch get.
+const maxBatchIDs = maxPageLimit
+
+// GetBooksByID serves POST /books/_mget, a batch get for ID lists too long
+// for GET /books?ids=. The body is {"ids": ["...", ...]}, with an optional
+// "fields" array like ?fields=. Being a POST, it ignores If-None-Match and
+// If-Modified-Since.
+func (bc *BookController) GetBooksByID(c *gin.Context) {
+	var req struct {
+		IDs    []string `json:"ids"`
+		Fields []string `json:"fields"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(badRequest("Request body must be an object with an ids array"))
+		return
+	}
+
+	var fields []string
+	if req.Fields != nil {
+		var err error
+		fields, err = parseFields(strings.Join(req.Fields, ","))
+		if err != nil {
+			c.Error(badRequest(err.Error()))
+			return
+		}
+	}
+	body, err := bc.batchGet(c, req.IDs, fields)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// batchGet returns the response body of a batch get: the books with the
+// given IDs, in the order asked for, and separately the IDs of the books
+// that do not exist. fields limits the fields sent as parsed by
+// parseFields. Errors are ready to report.
+func (bc *BookController) batchGet(c *gin.Context, rawIDs []string, fields []string) (gin.H, error) {
+	ids, err := parseBookIDs(rawIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), bc.opts.Timeout)
+	defer cancel()
+
+	books, err := bc.store.GetMany(ctx, ids, storedFields(fields))
+	if err != nil {
+		return nil, storeError("Failed to fetch books", err)
+	}
+
+	byID := make(map[bson.ObjectID]models.Book, len(books))
+	for _, book := range books {
+		byID[book.ID] = book
+	}
+
+	found := make([]models.Book, 0, len(books))
+	missing := []string{}
+	for _, id := range ids {
+		book, ok := byID[id]
+		if !ok {
+			missing = append(missing, id.Hex())
+			continue
+		}
+		found = append(found, book)
+	}
+	return gin.H{"data": projectBooks(found, fields), "missing": missing}, nil
+}
+
+// parseBookIDs checks a list of book IDs, reporting every invalid one, and
+// drops repeats.
+func parseBookIDs(raw []string) ([]bson.ObjectID, error) {
+	if len(raw) == 0 || len(raw) > maxBatchIDs {
+		return nil, badRequest(fmt.Sprintf("ids must list between 1 and %d book IDs", maxBatchIDs))
+	}
+
+	ids := make([]bson.ObjectID, 0, len(raw))
+	seen := make(map[bson.ObjectID]bool, len(raw))
+	var errs []fieldError
+	for i, s := range raw {
+		id, err := bson.ObjectIDFromHex(strings.TrimSpace(s))
+		if err != nil {
+			errs = append(errs, fieldError{
+				Field:   fmt.Sprintf("ids[%d]", i),
+				Rule:    "objectid",
+				Message: fmt.Sprintf("%q is not a valid book ID", s),
+			})
+			continue
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	if len(errs) > 0 {
+		e := badRequest("One or more book IDs are invalid")
+		e.Errors = errs
+		return nil, e
+	}
+	return ids, nil
+}
@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"go-crud/models"
+)
+
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+// PatchBook applies an RFC 7396 merge patch or an RFC 6902 JSON patch to a
+// book, chosen by Content-Type. The patched book is validated like a PUT
+// body before it is stored.
+func (bc *BookController) PatchBook(c *gin.Context) {
+	bookID := c.Param("id")
+	objectID, err := bson.ObjectIDFromHex(bookID)
+	if err != nil {
+		c.Error(badRequest("Invalid book ID"))
+		return
+	}
+
+	contentType := c.ContentType()
+	if contentType != mergePatchType && contentType != jsonPatchType {
+		c.Header("Accept-Patch", mergePatchType+", "+jsonPatchType)
+		c.Error(newAPIError(http.StatusUnsupportedMediaType, "Content-Type must be "+mergePatchType+" or "+jsonPatchType))
+		return
+	}
+
+	patch, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.Error(badRequest("Failed to read request body"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), bc.opts.Timeout)
+	defer cancel()
+
+	before, updatedBook, err := bc.modifyBook(ctx, c, objectID, func(current models.Book) (models.Book, error) {
+		patched, err := applyPatch(current, contentType, patch)
+		if err != nil {
+			return models.Book{}, err
+		}
+
+		// Only write over the version the patch was applied to
+		book, err := bc.store.Update(ctx, objectID, patched, current.Version)
+		if err != nil {
+			return models.Book{}, storeError("Failed to update book", err)
+		}
+		return book, nil
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if err := bc.recordHistory(ctx, c, models.ActionUpdate, before, updatedBook); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("ETag", bookETag(updatedBook))
+	c.JSON(http.StatusOK, updatedBook)
+}
+
+// applyPatch returns book with patch applied. Errors describe why the
+// patch was rejected and are safe to report to the client.
+func applyPatch(book models.Book, contentType string, patch []byte) (models.Book, error) {
+	doc, err := json.Marshal(book)
+	if err != nil {
+		return models.Book{}, internalError("Failed to encode book", err)
+	}
+
+	var patchedDoc []byte
+	if contentType == mergePatchType {
+		patchedDoc, err = jsonpatch.MergePatch(doc, patch)
+		if err != nil {
+			return models.Book{}, invalidPatch(http.StatusBadRequest, "Invalid merge patch document")
+		}
+	} else {
+		ops, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return models.Book{}, invalidPatch(http.StatusBadRequest, "Invalid JSON patch document")
+		}
+		patchedDoc, err = ops.Apply(doc)
+		if errors.Is(err, jsonpatch.ErrTestFailed) {
+			e := newAPIError(http.StatusConflict, "JSON patch test operation failed")
+			e.Type = problemPatchTestFails
+			return models.Book{}, e
+		}
+		if err != nil {
+			return models.Book{}, invalidPatch(http.StatusUnprocessableEntity, "Failed to apply JSON patch: "+err.Error())
+		}
+	}
+
+	var patched models.Book
+	dec := json.NewDecoder(bytes.NewReader(patchedDoc))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&patched); err != nil {
+		return models.Book{}, invalidPatch(http.StatusUnprocessableEntity, "Patched book is invalid: "+err.Error())
+	}
+	if patched.ID != book.ID {
+		return models.Book{}, invalidPatch(http.StatusUnprocessableEntity, "Book ID cannot be changed")
+	}
+	if patched.Version != book.Version {
+		return models.Book{}, invalidPatch(http.StatusUnprocessableEntity, "Book version cannot be changed")
+	}
+	if err := binding.Validator.ValidateStruct(&patched); err != nil {
+		return models.Book{}, err
+	}
+
+	return patched, nil
+}
+
+func invalidPatch(status int, detail string) *APIError {
+	e := newAPIError(status, detail)
+	e.Type = problemInvalidPatch
+	return e
+}
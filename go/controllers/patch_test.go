@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"go-crud/models"
+)
+
+func TestApplyPatch(t *testing.T) {
+	book := models.Book{ID: bson.NewObjectID(), Title: "Dune", Author: "Frank Herbert", Year: 1965, Version: 3}
+
+	tests := []struct {
+		name        string
+		contentType string
+		patch       string
+		want        models.Book
+	}{
+		{
+			name:        "merge patch",
+			contentType: mergePatchType,
+			patch:       `{"title": "Dune Messiah", "year": 1969}`,
+			want:        models.Book{ID: book.ID, Title: "Dune Messiah", Author: "Frank Herbert", Year: 1969, Version: 3},
+		},
+		{
+			name:        "json patch",
+			contentType: jsonPatchType,
+			patch:       `[{"op": "test", "path": "/year", "value": 1965}, {"op": "replace", "path": "/author", "value": "F. Herbert"}]`,
+			want:        models.Book{ID: book.ID, Title: "Dune", Author: "F. Herbert", Year: 1965, Version: 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyPatch(book, tt.contentType, []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("applyPatch: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("applyPatch = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPatchErrors(t *testing.T) {
+	book := models.Book{ID: bson.NewObjectID(), Title: "Dune", Author: "Frank Herbert", Year: 1965, Version: 3}
+
+	tests := []struct {
+		name        string
+		contentType string
+		patch       string
+		status      int
+		problemType string
+	}{
+		{"invalid merge patch", mergePatchType, `{"title":`, http.StatusBadRequest, problemInvalidPatch},
+		{"invalid json patch", jsonPatchType, `{"op": "replace"}`, http.StatusBadRequest, problemInvalidPatch},
+		{"failed test", jsonPatchType, `[{"op": "test", "path": "/year", "value": 1966}]`, http.StatusConflict, problemPatchTestFails},
+		{"missing path", jsonPatchType, `[{"op": "remove", "path": "/publisher"}]`, http.StatusUnprocessableEntity, problemInvalidPatch},
+		{"unknown field", mergePatchType, `{"publisher": "Chilton"}`, http.StatusUnprocessableEntity, problemInvalidPatch},
+		{"changed id", mergePatchType, `{"id": "000000000000000000000001"}`, http.StatusUnprocessableEntity, problemInvalidPatch},
+		{"changed version", mergePatchType, `{"version": 4}`, http.StatusUnprocessableEntity, problemInvalidPatch},
+		{"invalid book", mergePatchType, `{"title": ""}`, http.StatusUnprocessableEntity, problemValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := applyPatch(book, tt.contentType, []byte(tt.patch))
+			if err == nil {
+				t.Fatal("applyPatch succeeded, want an error")
+			}
+			apiErr := toAPIError(err)
+			if apiErr.Status != tt.status || apiErr.Type != tt.problemType {
+				t.Errorf("applyPatch error = %d %s, want %d %s", apiErr.Status, apiErr.Type, tt.status, tt.problemType)
+			}
+		})
+	}
+}
+
+func TestPatchBook(t *testing.T) {
+	router, _ := newTestRouter(t, RouterOptions{})
+	book := createBook(t, router, `{"title": "Dune", "author": "Frank Herbert", "year": 1965}`)
+	path := "/books/" + book.ID.Hex()
+
+	w := serve(router, http.MethodPatch, path, `{"year": 1966}`, "Content-Type", mergePatchType)
+	if w.Code != http.StatusOK {
+		t.Fatalf("merge patch: status %d: %s", w.Code, w.Body)
+	}
+	var patched models.Book
+	json.Unmarshal(w.Body.Bytes(), &patched)
+	if patched.Year != 1966 || patched.Title != "Dune" || patched.Version != 2 {
+		t.Errorf("patched book = %+v, want year 1966 at version 2", patched)
+	}
+
+	w = serve(router, http.MethodPatch, path, `{"year": 1967}`)
+	if w.Code != http.StatusUnsupportedMediaType || w.Header().Get("Accept-Patch") == "" {
+		t.Errorf("patch as application/json: status %d, Accept-Patch %q, want 415 with Accept-Patch", w.Code, w.Header().Get("Accept-Patch"))
+	}
+}
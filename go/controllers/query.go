@@ -0,0 +1,264 @@
+package controllers
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-crud/store"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// Kinds of listField values, which decide how filter values are parsed.
+const (
+	kindString = iota
+	kindInt
+	// kindTime values are RFC 3339 timestamps.
+	kindTime
+	kindID
+)
+
+// listField describes a book field that GET /books can sort and filter on.
+type listField struct {
+	// stored is the field name in the store.
+	stored string
+	kind   int
+	// sortOnly fields cannot be used as filters.
+	sortOnly bool
+}
+
+// listFields maps the JSON names accepted in query parameters to fields.
+var listFields = map[string]listField{
+	"id":        {stored: "_id", kind: kindID, sortOnly: true},
+	"title":     {stored: "title", kind: kindString},
+	"author":    {stored: "author", kind: kindString},
+	"year":      {stored: "year", kind: kindInt},
+	"createdAt": {stored: "createdAt", kind: kindTime},
+	"updatedAt": {stored: "updatedAt", kind: kindTime},
+	"deletedAt": {stored: "deletedAt", kind: kindTime},
+}
+
+// rangeSuffixes maps filter parameter suffixes such as year_gte to operators.
+var rangeSuffixes = map[string]string{
+	"_gt":  store.OpGt,
+	"_gte": store.OpGte,
+	"_lt":  store.OpLt,
+	"_lte": store.OpLte,
+}
+
+// listQuery is the parsed form of the GET /books query string.
+type listQuery struct {
+	opts  store.ListOptions
+	limit int64
+	// useOffset is set when the client paged with offset rather than page.
+	useOffset bool
+	// useCursor is set when the client asked for keyset paging by passing
+	// a cursor parameter, which is empty for the first page.
+	useCursor bool
+	// fields are the JSON fields asked for with ?fields=, nil for all.
+	fields []string
+}
+
+// parseListQuery reads page, limit, offset, cursor, sort, fields and field
+// filters from q.
+func parseListQuery(q url.Values) (listQuery, error) {
+	var lq listQuery
+
+	limit := int64(defaultPageLimit)
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 || n > maxPageLimit {
+			return lq, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
+		}
+		limit = n
+	}
+	lq.opts.Limit = limit
+	lq.limit = limit
+
+	_, lq.useCursor = q["cursor"]
+	if lq.useCursor && (q.Has("page") || q.Has("offset")) {
+		return lq, fmt.Errorf("cursor cannot be combined with page or offset")
+	}
+
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return lq, fmt.Errorf("offset must be a non-negative integer")
+		}
+		// Paging links add limit to the offset
+		if n > math.MaxInt64-limit {
+			return lq, fmt.Errorf("offset is too large")
+		}
+		lq.opts.Offset = n
+		lq.useOffset = true
+	} else if v := q.Get("page"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 {
+			return lq, fmt.Errorf("page must be a positive integer")
+		}
+		// The offset of the page, and of the next one for its link, must
+		// not overflow
+		if n-1 >= math.MaxInt64/limit {
+			return lq, fmt.Errorf("page is too large")
+		}
+		lq.opts.Offset = (n - 1) * limit
+	}
+
+	if v := q.Get("sort"); v != "" {
+		for _, name := range strings.Split(v, ",") {
+			desc := strings.HasPrefix(name, "-")
+			name = strings.TrimPrefix(name, "-")
+			field, ok := listFields[name]
+			if !ok {
+				return lq, fmt.Errorf("cannot sort by %q", name)
+			}
+			lq.opts.Sort = append(lq.opts.Sort, store.SortField{Field: field.stored, Desc: desc})
+		}
+	}
+
+	if lq.useCursor {
+		// Fetch one extra book to learn whether there is a next page
+		lq.opts.Limit = limit + 1
+		if v := q.Get("cursor"); v != "" {
+			after, err := decodeCursor(v, q.Get("sort"), lq.opts.Sort)
+			if err != nil {
+				return lq, err
+			}
+			lq.opts.After = after
+		}
+	}
+
+	fields, err := parseFields(q.Get("fields"))
+	if err != nil {
+		return lq, err
+	}
+	lq.fields = fields
+	if fields != nil {
+		// Cursors are built from the sort keys, so load those too
+		sortFields := make([]string, len(lq.opts.Sort))
+		for i, sf := range lq.opts.Sort {
+			sortFields[i] = sf.Field
+		}
+		lq.opts.Fields = storedFields(fields, sortFields...)
+	}
+
+	for key, values := range q {
+		switch key {
+		case "page", "limit", "offset", "cursor", "sort", "fields":
+			continue
+		}
+
+		name, op := key, store.OpEq
+		for suffix, rangeOp := range rangeSuffixes {
+			if strings.HasSuffix(key, suffix) {
+				name, op = strings.TrimSuffix(key, suffix), rangeOp
+				break
+			}
+		}
+
+		field, ok := listFields[name]
+		if !ok || field.sortOnly {
+			return lq, fmt.Errorf("unknown query parameter %q", key)
+		}
+
+		var value any = values[0]
+		switch field.kind {
+		case kindInt:
+			n, err := strconv.Atoi(values[0])
+			if err != nil {
+				return lq, fmt.Errorf("%s must be an integer", key)
+			}
+			value = n
+		case kindTime:
+			t, err := time.Parse(time.RFC3339, values[0])
+			if err != nil {
+				return lq, fmt.Errorf("%s must be an RFC 3339 timestamp", key)
+			}
+			value = t.UTC()
+		}
+		lq.opts.Filters = append(lq.opts.Filters, store.Filter{Field: field.stored, Op: op, Value: value})
+	}
+
+	return lq, nil
+}
+
+// bookPage is the GET /books response body. Page is left out in cursor
+// mode, where NextCursor resumes the listing instead.
+type bookPage struct {
+	// Data holds []models.Book, or the projected books with ?fields=.
+	Data       any       `json:"data"`
+	Total      int64     `json:"total"`
+	Page       int64     `json:"page,omitempty"`
+	Limit      int64     `json:"limit"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+	Links      pageLinks `json:"links"`
+}
+
+type pageLinks struct {
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// newBookPage wraps a list result with its paging metadata. Links keep the
+// request's other parameters and page the same way the client did.
+func newBookPage(u *url.URL, lq listQuery, res store.ListResult) bookPage {
+	if lq.useCursor {
+		return newCursorPage(u, lq, res)
+	}
+
+	offset, limit := lq.opts.Offset, lq.limit
+	page := bookPage{
+		Data:  projectBooks(res.Books, lq.fields),
+		Total: res.Total,
+		Page:  offset/limit + 1,
+		Limit: limit,
+	}
+
+	if offset+limit < res.Total {
+		page.Links.Next = pageLink(u, lq.useOffset, offset+limit, limit)
+	}
+	if offset > 0 {
+		page.Links.Prev = pageLink(u, lq.useOffset, max(offset-limit, 0), limit)
+	}
+	return page
+}
+
+// newCursorPage builds a keyset page. Cursors only move forward, so there
+// is no prev link.
+func newCursorPage(u *url.URL, lq listQuery, res store.ListResult) bookPage {
+	books := res.Books
+	page := bookPage{
+		Total: res.Total,
+		Limit: lq.limit,
+	}
+
+	if int64(len(books)) > lq.limit {
+		books = books[:lq.limit]
+		last := books[len(books)-1]
+		page.NextCursor = encodeCursor(last, u.Query().Get("sort"), lq.opts.Sort)
+
+		q := u.Query()
+		q.Set("cursor", page.NextCursor)
+		page.Links.Next = u.Path + "?" + q.Encode()
+	}
+	page.Data = projectBooks(books, lq.fields)
+	return page
+}
+
+func pageLink(u *url.URL, useOffset bool, offset, limit int64) string {
+	q := u.Query()
+	q.Set("limit", strconv.FormatInt(limit, 10))
+	if useOffset {
+		q.Set("offset", strconv.FormatInt(offset, 10))
+	} else {
+		q.Set("page", strconv.FormatInt(offset/limit+1, 10))
+	}
+	return u.Path + "?" + q.Encode()
+}
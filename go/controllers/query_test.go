@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go-crud/store"
+)
+
+func TestParseListQuery(t *testing.T) {
+	tests := []struct {
+		query   string
+		offset  int64
+		limit   int64
+		sort    []store.SortField
+		filters []store.Filter
+	}{
+		{query: "", offset: 0, limit: defaultPageLimit},
+		{query: "page=3&limit=10", offset: 20, limit: 10},
+		{query: "offset=7&limit=5", offset: 7, limit: 5},
+		{
+			query: "sort=-year,title",
+			limit: defaultPageLimit,
+			sort:  []store.SortField{{Field: "year", Desc: true}, {Field: "title"}},
+		},
+		{
+			query:   "author=Tolkien",
+			limit:   defaultPageLimit,
+			filters: []store.Filter{{Field: "author", Op: store.OpEq, Value: "Tolkien"}},
+		},
+		{
+			query:   "year_gte=1950",
+			limit:   defaultPageLimit,
+			filters: []store.Filter{{Field: "year", Op: store.OpGte, Value: 1950}},
+		},
+		{
+			query:   "createdAt_lt=2024-01-02T03:04:05%2B02:00",
+			limit:   defaultPageLimit,
+			filters: []store.Filter{{Field: "createdAt", Op: store.OpLt, Value: time.Date(2024, 1, 2, 1, 4, 5, 0, time.UTC)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, _ := url.ParseQuery(tt.query)
+			lq, err := parseListQuery(q)
+			if err != nil {
+				t.Fatalf("parseListQuery(%q): %v", tt.query, err)
+			}
+			if lq.opts.Offset != tt.offset || lq.limit != tt.limit {
+				t.Errorf("offset, limit = %d, %d, want %d, %d", lq.opts.Offset, lq.limit, tt.offset, tt.limit)
+			}
+			if !reflect.DeepEqual(lq.opts.Sort, tt.sort) {
+				t.Errorf("sort = %v, want %v", lq.opts.Sort, tt.sort)
+			}
+			if !reflect.DeepEqual(lq.opts.Filters, tt.filters) {
+				t.Errorf("filters = %v, want %v", lq.opts.Filters, tt.filters)
+			}
+		})
+	}
+}
+
+func TestParseListQueryErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"limit=0", "limit must be between"},
+		{"limit=101", "limit must be between"},
+		{"page=0", "page must be a positive integer"},
+		{"page=922337203685477581&limit=10", "page is too large"},
+		{"offset=-1", "offset must be a non-negative integer"},
+		{"offset=9223372036854775800", "offset is too large"},
+		{"sort=publisher", `cannot sort by "publisher"`},
+		{"publisher=Penguin", `unknown query parameter "publisher"`},
+		{"id=1", `unknown query parameter "id"`},
+		{"year=abc", "year must be an integer"},
+		{"updatedAt_gt=yesterday", "updatedAt_gt must be an RFC 3339 timestamp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, _ := url.ParseQuery(tt.query)
+			_, err := parseListQuery(q)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseListQuery(%q) error = %v, want %q", tt.query, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewBookPageLinks(t *testing.T) {
+	tests := []struct {
+		query      string
+		total      int64
+		next, prev string
+	}{
+		{"limit=10", 25, "/books?limit=10&page=2", ""},
+		{"page=2&limit=10&author=Austen", 25, "/books?author=Austen&limit=10&page=3", "/books?author=Austen&limit=10&page=1"},
+		{"page=3&limit=10", 25, "", "/books?limit=10&page=2"},
+		{"offset=5&limit=10", 25, "/books?limit=10&offset=15", "/books?limit=10&offset=0"},
+		{"limit=10", 10, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			u, _ := url.Parse("/books?" + tt.query)
+			lq, err := parseListQuery(u.Query())
+			if err != nil {
+				t.Fatal(err)
+			}
+			page := newBookPage(u, lq, store.ListResult{Total: tt.total})
+			if page.Links.Next != tt.next || page.Links.Prev != tt.prev {
+				t.Errorf("links = %+v, want next %q and prev %q", page.Links, tt.next, tt.prev)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"go-crud/models"
+	"go-crud/store"
+)
+
+// bookAsOf rebuilds a book as it was at asOf from its revisions: the book
+// left by the last change recorded at or before that time.
+func (bc *BookController) bookAsOf(ctx context.Context, id bson.ObjectID, asOf time.Time) (models.Book, error) {
+	entries, err := bc.store.History(ctx, id)
+	if err != nil {
+		return models.Book{}, storeError("Failed to fetch book history", err)
+	}
+
+	var rev models.HistoryEntry
+	for _, entry := range entries {
+		if entry.Timestamp.After(asOf) {
+			break
+		}
+		rev = entry
+	}
+
+	book := rev.Book
+	if !book.ID.IsZero() {
+		recorded, err := bc.nextChangeRecorded(ctx, id, entries, rev, asOf)
+		if err != nil {
+			return models.Book{}, err
+		}
+		if !recorded {
+			// The missing change may have been made before asOf
+			return models.Book{}, internalError("The book's history is missing a revision, so its state at that time is unknown", errHistoryGap)
+		}
+	}
+
+	switch {
+	case book.ID.IsZero():
+		return models.Book{}, newAPIError(http.StatusNotFound, "Book has no revision at that time")
+	case !book.DeletedAt.IsZero():
+		return models.Book{}, newAPIError(http.StatusNotFound, "Book was in the trash at that time")
+	}
+	return book, nil
+}
+
+// errHistoryGap is the internal cause of as_of reads refused because a
+// revision was not recorded.
+var errHistoryGap = errors.New("book history is missing a revision")
+
+// nextChangeRecorded reports whether the history has the change that
+// followed rev, or, when rev is the latest revision, whether the book is
+// still as rev left it or changed once since, after asOf, as when that
+// change's revision is still being recorded. Otherwise a change was made
+// without its revision being recorded, possibly before asOf.
+func (bc *BookController) nextChangeRecorded(ctx context.Context, id bson.ObjectID, entries []models.HistoryEntry, rev models.HistoryEntry, asOf time.Time) (bool, error) {
+	latest := true
+	for _, entry := range entries {
+		switch {
+		// A purge keeps the version of the book it removed
+		case entry.Version == rev.Version+1, entry.Version == rev.Version && entry.Action == models.ActionPurge:
+			return true, nil
+		case entry.Version > rev.Version:
+			latest = false
+		}
+	}
+	if !latest {
+		return false, nil
+	}
+
+	current, err := bc.store.Get(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		current, err = bc.trashedBook(ctx, id)
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		// Purged without a purge revision
+		return false, nil
+	}
+	if err != nil {
+		return false, storeError("Failed to find book", err)
+	}
+	return current.Version == rev.Version ||
+		current.Version == rev.Version+1 && current.UpdatedAt.After(asOf), nil
+}
+
+// revision finds the revision of a book with the given number.
+func (bc *BookController) revision(ctx context.Context, id bson.ObjectID, number int64) (models.Book, error) {
+	entries, err := bc.store.History(ctx, id)
+	if err != nil {
+		return models.Book{}, storeError("Failed to fetch book history", err)
+	}
+
+	for _, entry := range entries {
+		if entry.Version == number && !entry.Book.ID.IsZero() {
+			return entry.Book, nil
+		}
+	}
+	return models.Book{}, newAPIError(http.StatusNotFound, fmt.Sprintf("Revision %d not found", number))
+}
+
+// RevertBook rolls a book back to the fields of an earlier revision, given
+// by ?revision=N. The rollback is a new write, so it gets a revision of its
+// own and can itself be reverted.
+func (bc *BookController) RevertBook(c *gin.Context) {
+	objectID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.Error(badRequest("Invalid book ID"))
+		return
+	}
+
+	number, err := strconv.ParseInt(c.Query("revision"), 10, 64)
+	if err != nil || number < 1 {
+		c.Error(badRequest("revision must be a positive integer"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), bc.opts.Timeout)
+	defer cancel()
+
+	target, err := bc.revision(ctx, objectID, number)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if !target.DeletedAt.IsZero() {
+		c.Error(newAPIError(http.StatusConflict, fmt.Sprintf("Revision %d is in the trash; restore the book instead", number)))
+		return
+	}
+
+	before, reverted, err := bc.modifyBook(ctx, c, objectID, func(current models.Book) (models.Book, error) {
+		book, err := bc.store.Update(ctx, objectID, target, current.Version)
+		if err != nil {
+			return models.Book{}, storeError("Failed to revert book", err)
+		}
+		return book, nil
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if err := bc.recordHistory(ctx, c, models.ActionRevert, before, reverted); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("ETag", bookETag(reverted))
+	c.JSON(http.StatusOK, reverted)
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-crud/models"
+	"go-crud/store"
+)
+
+// historyFailingStore fails AddHistory while fail is set.
+type historyFailingStore struct {
+	*store.MemoryStore
+	fail bool
+}
+
+func (s *historyFailingStore) AddHistory(ctx context.Context, entries ...models.HistoryEntry) error {
+	if s.fail {
+		return errors.New("history unavailable")
+	}
+	return s.MemoryStore.AddHistory(ctx, entries...)
+}
+
+// decodeBook decodes the book in a response, failing the test unless its
+// status is want.
+func decodeBook(t *testing.T, w *httptest.ResponseRecorder, want int) models.Book {
+	t.Helper()
+	if w.Code != want {
+		t.Fatalf("status %d, want %d: %s", w.Code, want, w.Body)
+	}
+	var book models.Book
+	json.Unmarshal(w.Body.Bytes(), &book)
+	return book
+}
+
+func asOf(ts time.Time) string {
+	return "?as_of=" + ts.UTC().Format(time.RFC3339Nano)
+}
+
+func TestGetBookAsOfAndRevert(t *testing.T) {
+	router, _ := newTestRouter(t, RouterOptions{})
+	v1 := createBook(t, router, `{"title": "Dune", "author": "Frank Herbert", "year": 1965}`)
+	path := "/books/" + v1.ID.Hex()
+	time.Sleep(5 * time.Millisecond)
+	w := serve(router, http.MethodPut, path, `{"title": "Dune Messiah", "author": "Frank Herbert", "year": 1969}`)
+	v2 := decodeBook(t, w, http.StatusOK)
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		title  string
+	}{
+		{"before the create", asOf(v1.CreatedAt.Add(-time.Second)), http.StatusNotFound, ""},
+		{"at the create", asOf(v1.UpdatedAt), http.StatusOK, "Dune"},
+		{"between the writes", asOf(v2.UpdatedAt.Add(-time.Millisecond)), http.StatusOK, "Dune"},
+		{"at the update", asOf(v2.UpdatedAt), http.StatusOK, "Dune Messiah"},
+		{"not a time", "?as_of=yesterday", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(router, http.MethodGet, path+tt.query, "")
+			book := decodeBook(t, w, tt.status)
+			if tt.status == http.StatusOK && book.Title != tt.title {
+				t.Errorf("title %q, want %q", book.Title, tt.title)
+			}
+		})
+	}
+
+	w = serve(router, http.MethodPost, path+"/revert?revision=1", "")
+	reverted := decodeBook(t, w, http.StatusOK)
+	if reverted.Title != "Dune" || reverted.Year != 1965 || reverted.Version != 3 {
+		t.Errorf("reverted book = %+v, want revision 1's fields at version 3", reverted)
+	}
+	if w := serve(router, http.MethodPost, path+"/revert?revision=9", ""); w.Code != http.StatusNotFound {
+		t.Errorf("revert to a missing revision: status %d, want 404", w.Code)
+	}
+
+	w = serve(router, http.MethodGet, path+"/history", "")
+	var history struct {
+		Data []models.HistoryEntry `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &history)
+	if n := len(history.Data); n != 3 || history.Data[2].Action != models.ActionRevert || history.Data[2].Version != 3 {
+		t.Errorf("history = %+v, want create, update and a revert at version 3", history.Data)
+	}
+
+	serve(router, http.MethodDelete, path, "")
+	if w := serve(router, http.MethodGet, path+asOf(time.Now()), ""); w.Code != http.StatusNotFound {
+		t.Errorf("as_of after the delete: status %d, want 404", w.Code)
+	}
+}
+
+func TestHistoryFailures(t *testing.T) {
+	s := &historyFailingStore{MemoryStore: store.NewMemoryStore()}
+	router := NewRouter(RouterOptions{Store: s})
+	book := createBook(t, router, `{"title": "Dune", "author": "Frank Herbert", "year": 1965}`)
+	path := "/books/" + book.ID.Hex()
+	time.Sleep(5 * time.Millisecond)
+
+	s.fail = true
+	w := serve(router, http.MethodPut, path, `{"title": "Dune Messiah", "author": "Frank Herbert", "year": 1969}`)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("update whose revision is lost: status %d, want 500", w.Code)
+	}
+	s.fail = false
+
+	// The lost update came after this time, so the book's state then is
+	// still known
+	w = serve(router, http.MethodGet, path+asOf(book.UpdatedAt), "")
+	if got := decodeBook(t, w, http.StatusOK); got.Title != "Dune" {
+		t.Errorf("as_of before the lost update: title %q, want Dune", got.Title)
+	}
+	if w := serve(router, http.MethodGet, path+asOf(time.Now()), ""); w.Code != http.StatusInternalServerError {
+		t.Errorf("as_of after the lost update: status %d, want 500", w.Code)
+	}
+}
@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+
+	"go-crud/logging"
+	"go-crud/metrics"
+	"go-crud/store"
+)
+
+// RouterOptions configures the engine built by NewRouter.
+type RouterOptions struct {
+	// Store backs the books API. It is required.
+	Store store.BookStore
+	// Timeout bounds each store call; zero means defaultTimeout.
+	Timeout time.Duration
+	// AllowOrigins lists the CORS origins; empty disables CORS.
+	AllowOrigins []string
+	// AdminToken is the bearer token that grants admin-only operations
+	// such as purging books; empty disables them.
+	AdminToken string
+	// IdempotencyTTL is how long responses to POST /books with an
+	// Idempotency-Key are kept; zero means defaultIdempotencyTTL.
+	IdempotencyTTL time.Duration
+	// Ready reports whether Store can serve requests; until it does the
+	// book endpoints answer 503 and /readyz reports not ready. Nil means
+	// always ready.
+	Ready func() bool
+	// HealthChecks are the dependencies /readyz checks besides Store.
+	HealthChecks []HealthCheck
+	// HealthTimeout bounds each /readyz check; zero means
+	// defaultHealthTimeout.
+	HealthTimeout time.Duration
+	// HealthDetail is how much /readyz tells about its checks, one of the
+	// HealthDetail levels; empty means HealthDetailStatus.
+	HealthDetail string
+	// Metrics, when set, records every request and is served under
+	// /metrics. Wrap Store with Metrics.Store to time its calls too.
+	Metrics *metrics.Metrics
+}
+
+// NewRouter returns a gin engine serving the books API under /books, with
+// liveness under /healthz, readiness under /readyz and, when enabled,
+// metrics under /metrics. Requests are logged with slog under a request
+// ID. Programs with their own engine can call RegisterRoutes instead.
+func NewRouter(opts RouterOptions) *gin.Engine {
+	router := gin.New()
+	router.Use(logging.RequestID(), logging.AccessLog())
+	if opts.Metrics != nil {
+		// Wrap ErrorHandler and Recovery so that the final status of every
+		// request is recorded, panics included
+		router.Use(opts.Metrics.Middleware())
+	}
+	// ErrorHandler comes before Recovery so that panics, unknown routes
+	// and methods are reported as problems like the books API's errors
+	router.Use(ErrorHandler(), logging.Recovery())
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(func(c *gin.Context) {
+		c.Error(newAPIError(http.StatusNotFound, "No endpoint matches "+c.Request.URL.Path))
+	})
+	router.NoMethod(func(c *gin.Context) {
+		c.Error(newAPIError(http.StatusMethodNotAllowed, c.Request.Method+" is not allowed on "+c.Request.URL.Path))
+	})
+	if opts.Metrics != nil {
+		router.GET("/metrics", gin.WrapH(opts.Metrics.Handler()))
+	}
+
+	if len(opts.AllowOrigins) > 0 {
+		// CORS configuration
+		config := cors.DefaultConfig()
+		config.AllowOrigins = opts.AllowOrigins
+		config.AllowMethods = []string{"GET", "POST",
+			"PUT", "PATCH", "DELETE", "OPTIONS"}
+		config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization",
+			"If-Match", "If-None-Match", "If-Modified-Since", "X-Actor", "Idempotency-Key", logging.RequestIDHeader}
+		config.ExposeHeaders = []string{"Content-Length", "ETag", "Last-Modified", "Idempotent-Replayed", logging.RequestIDHeader}
+		config.AllowCredentials = true
+
+		router.Use(cors.New(config))
+	}
+
+	NewBookController(opts.Store, ControllerOptions{
+		Timeout:        opts.Timeout,
+		AdminToken:     opts.AdminToken,
+		IdempotencyTTL: opts.IdempotencyTTL,
+		Ready:          opts.Ready,
+	}).RegisterRoutes(router.Group("/books"))
+
+	timeout := opts.HealthTimeout
+	if timeout <= 0 {
+		timeout = defaultHealthTimeout
+	}
+	detail := opts.HealthDetail
+	if detail == "" {
+		detail = HealthDetailStatus
+	}
+	checks := append([]HealthCheck{storeHealthCheck(opts.Store, opts.Ready)}, opts.HealthChecks...)
+	router.GET("/healthz", liveHandler)
+	router.GET("/readyz", readyHandler(checks, timeout, detail))
+	return router
+}
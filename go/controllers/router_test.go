@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"go-crud/metrics"
+	"go-crud/models"
+	"go-crud/store"
+)
+
+// newTestRouter returns a router serving the books API from a new
+// MemoryStore.
+func newTestRouter(t *testing.T, opts RouterOptions) (*gin.Engine, *store.MemoryStore) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	s := store.NewMemoryStore()
+	opts.Store = s
+	return NewRouter(opts), s
+}
+
+// serve sends a request with the given body, empty for none, and headers
+// given as name, value pairs.
+func serve(router http.Handler, method, target, body string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// createBook creates a book through the API and returns it as sent back.
+func createBook(t *testing.T, router http.Handler, body string) models.Book {
+	t.Helper()
+	w := serve(router, http.MethodPost, "/books", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST /books: status %d: %s", w.Code, w.Body)
+	}
+	var book models.Book
+	if err := json.Unmarshal(w.Body.Bytes(), &book); err != nil {
+		t.Fatal(err)
+	}
+	return book
+}
+
+func TestRouterCountsPanics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := metrics.New()
+	router := NewRouter(RouterOptions{Store: store.NewMemoryStore(), Metrics: m})
+	router.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	want := `http_requests_total{method="GET",route="/panic",status="500"} 1`
+	if !strings.Contains(w.Body.String(), want) {
+		t.Errorf("/metrics does not contain %s", want)
+	}
+}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"go-crud/models"
+)
+
+// searchHit is one GET /books/search result: the book, its relevance score
+// and, for each field that matched, the field text with the matching words
+// wrapped in <em> tags. The rest of the text is HTML-escaped.
+type searchHit struct {
+	models.Book
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+func (bc *BookController) SearchBooks(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.Error(badRequest("Search query q is required"))
+		return
+	}
+
+	limit := int64(defaultPageLimit)
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 || n > maxPageLimit {
+			c.Error(badRequest(fmt.Sprintf("limit must be between 1 and %d", maxPageLimit)))
+			return
+		}
+		limit = n
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), bc.opts.Timeout)
+	defer cancel()
+
+	res, err := bc.store.Search(ctx, query, limit)
+	if err != nil {
+		c.Error(storeError("Failed to search books", err))
+		return
+	}
+
+	terms := searchTerms(query)
+	results := make([]searchHit, len(res.Hits))
+	for i, hit := range res.Hits {
+		results[i] = searchHit{Book: hit.Book, Score: hit.Score, Highlights: map[string]string{}}
+		if h, ok := highlight(hit.Book.Title, terms); ok {
+			results[i].Highlights["title"] = h
+		}
+		if h, ok := highlight(hit.Book.Author, terms); ok {
+			results[i].Highlights["author"] = h
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": results, "total": res.Total})
+}
+
+// searchTerms matches any word of query, ignoring case.
+func searchTerms(query string) *regexp.Regexp {
+	words := strings.Fields(query)
+	for i, w := range words {
+		words[i] = regexp.QuoteMeta(strings.Trim(w, `"-`))
+	}
+	return regexp.MustCompile(`(?i)` + strings.Join(words, "|"))
+}
+
+// highlight wraps the parts of text matched by terms in <em> tags and
+// reports whether anything matched.
+func highlight(text string, terms *regexp.Regexp) (string, bool) {
+	matches := terms.FindAllStringIndex(text, -1)
+	if len(matches) == 0 {
+		return "", false
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		if m[0] == m[1] {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[last:m[0]]))
+		b.WriteString("<em>")
+		b.WriteString(html.EscapeString(text[m[0]:m[1]]))
+		b.WriteString("</em>")
+		last = m[1]
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return b.String(), last > 0
+}
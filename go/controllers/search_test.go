@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestSearchBooks(t *testing.T) {
+	router, _ := newTestRouter(t, RouterOptions{})
+	createBook(t, router, `{"title": "Emma", "author": "Jane Austen", "year": 1815}`)
+	createBook(t, router, `{"title": "Persuasion", "author": "Jane Austen", "year": 1817}`)
+	createBook(t, router, `{"title": "Dune", "author": "Frank Herbert", "year": 1965}`)
+
+	w := serve(router, http.MethodGet, "/books/search?q=austen&limit=1", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var body struct {
+		Data  []searchHit `json:"data"`
+		Total int64       `json:"total"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if len(body.Data) != 1 || body.Total != 2 {
+		t.Errorf("got %d hits with total %d, want 1 hit of 2", len(body.Data), body.Total)
+	}
+	if got := body.Data[0].Highlights["author"]; got != "Jane <em>Austen</em>" {
+		t.Errorf("author highlight = %q", got)
+	}
+
+	w = serve(router, http.MethodGet, "/books/search", "")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("search without q: status %d, want 400", w.Code)
+	}
+}
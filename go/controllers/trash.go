@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"go-crud/models"
+	"go-crud/store"
+)
+
+// GetTrash lists the books that were deleted but not yet purged. It takes
+// the same paging, sort and filter parameters as GetBooks.
+func (bc *BookController) GetTrash(c *gin.Context) {
+	bc.listBooks(c, true)
+}
+
+// RestoreBook takes a book out of the trash.
+func (bc *BookController) RestoreBook(c *gin.Context) {
+	objectID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.Error(badRequest("Invalid book ID"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), bc.opts.Timeout)
+	defer cancel()
+
+	trashed, err := bc.trashedBook(ctx, objectID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	book, err := bc.store.Restore(ctx, objectID)
+	if err != nil {
+		c.Error(storeError("Failed to restore book", err))
+		return
+	}
+	if err := bc.recordHistory(ctx, c, models.ActionRestore, trashed, book); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("ETag", bookETag(book))
+	c.JSON(http.StatusOK, book)
+}
+
+// trashedBook finds a book in the trash.
+func (bc *BookController) trashedBook(ctx context.Context, id bson.ObjectID) (models.Book, error) {
+	res, err := bc.store.List(ctx, store.ListOptions{
+		Filters: []store.Filter{{Field: "_id", Op: store.OpEq, Value: id}},
+		Limit:   1,
+		Trashed: true,
+	})
+	if err != nil {
+		return models.Book{}, storeError("Failed to find book", err)
+	}
+	if len(res.Books) == 0 {
+		return models.Book{}, storeError("Failed to find book", store.ErrNotFound)
+	}
+	return res.Books[0], nil
+}
+
+// purgeBook removes a book for good, whether or not it is in the trash.
+func (bc *BookController) purgeBook(c *gin.Context, id bson.ObjectID) {
+	if err := bc.requireAdmin(c); err != nil {
+		c.Error(err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), bc.opts.Timeout)
+	defer cancel()
+
+	book, err := bc.store.Purge(ctx, id)
+	if err != nil {
+		c.Error(storeError("Failed to purge book", err))
+		return
+	}
+	if err := bc.recordHistory(ctx, c, models.ActionPurge, book, models.Book{}); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Book purged"})
+}
+
+// requireAdmin checks that the request carries the admin bearer token.
+func (bc *BookController) requireAdmin(c *gin.Context) *APIError {
+	if bc.opts.AdminToken == "" {
+		return newAPIError(http.StatusForbidden, "Admin operations are disabled on this server")
+	}
+	if !bc.isAdmin(c) {
+		c.Header("WWW-Authenticate", `Bearer realm="books"`)
+		return newAPIError(http.StatusUnauthorized, "An admin token is required")
+	}
+	return nil
+}
+
+func (bc *BookController) isAdmin(c *gin.Context) bool {
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	return ok && bc.opts.AdminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(bc.opts.AdminToken)) == 1
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// listTotal returns the total of a GET /books or /books/trash response.
+func listTotal(t *testing.T, router http.Handler, target string) int64 {
+	t.Helper()
+	w := serve(router, http.MethodGet, target, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s: status %d: %s", target, w.Code, w.Body)
+	}
+	var page bookPage
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	return page.Total
+}
+
+func TestCreateBookIgnoresDeletedAt(t *testing.T) {
+	router, _ := newTestRouter(t, RouterOptions{})
+	book := createBook(t, router, `{"title": "Dune", "author": "Frank Herbert", "year": 1965, "deletedAt": "2001-01-01T00:00:00Z"}`)
+	if !book.DeletedAt.IsZero() {
+		t.Errorf("created book has deletedAt %v, want none", book.DeletedAt)
+	}
+
+	if w := serve(router, http.MethodGet, "/books/"+book.ID.Hex(), ""); w.Code != http.StatusOK {
+		t.Errorf("GET of the created book: status %d, want 200", w.Code)
+	}
+	if n := listTotal(t, router, "/books/trash"); n != 0 {
+		t.Errorf("trash holds %d books, want none", n)
+	}
+}
+
+func TestTrashFilters(t *testing.T) {
+	router, _ := newTestRouter(t, RouterOptions{})
+	book := createBook(t, router, `{"title": "Dune", "author": "Frank Herbert", "year": 1965}`)
+	createBook(t, router, `{"title": "Emma", "author": "Jane Austen", "year": 1815}`)
+	if w := serve(router, http.MethodDelete, "/books/"+book.ID.Hex(), ""); w.Code != http.StatusOK {
+		t.Fatalf("DELETE: status %d: %s", w.Code, w.Body)
+	}
+
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	tests := []struct {
+		target string
+		want   int64
+	}{
+		{"/books", 1},
+		{"/books/trash", 1},
+		{"/books/trash?deletedAt_lt=" + future, 1},
+		{"/books/trash?deletedAt_lt=" + past, 0},
+		{"/books/trash?author=Jane%20Austen", 0},
+	}
+	for _, tt := range tests {
+		if n := listTotal(t, router, tt.target); n != tt.want {
+			t.Errorf("GET %s: total %d, want %d", tt.target, n, tt.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	// Report fields by their JSON names so clients can match them to inputs
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	v.RegisterValidation("notfuture", func(fl validator.FieldLevel) bool {
+		return fl.Field().Int() <= int64(time.Now().Year())
+	})
+}
+
+// fieldError is one failed validation rule, as returned to clients.
+type fieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// fieldErrors converts validator errors into fieldErrors. It reports false
+// when err is not a validation failure, e.g. malformed JSON.
+func fieldErrors(err error) ([]fieldError, bool) {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return nil, false
+	}
+
+	out := make([]fieldError, len(verrs))
+	for i, fe := range verrs {
+		out[i] = fieldError{
+			Field:   fe.Field(),
+			Rule:    fe.Tag(),
+			Message: fieldErrorMessage(fe),
+		}
+	}
+	return out, true
+}
+
+func fieldErrorMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", fe.Field())
+	case "max":
+		return fmt.Sprintf("%s must be at most %s characters", fe.Field(), fe.Param())
+	case "gte":
+		return fmt.Sprintf("%s must be at least %s", fe.Field(), fe.Param())
+	case "notfuture":
+		return fmt.Sprintf("%s cannot be in the future", fe.Field())
+	}
+	return fmt.Sprintf("%s is invalid", fe.Field())
+}
+
+// bindError reports a failed ShouldBindJSON: validation failures list
+// each invalid field, anything else is a malformed body.
+func bindError(err error) error {
+	if errs, ok := fieldErrors(err); ok {
+		return validationError(errs)
+	}
+	return badRequest("Invalid input data")
+}
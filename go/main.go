@@ -2,58 +2,201 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
 
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
 
+	"go-crud/config"
 	"go-crud/controllers"
+	"go-crud/logging"
+	"go-crud/metrics"
+	"go-crud/store"
 )
 
 func main() {
-	client, err := mongo.Connect(options.Client().ApplyURI("mongodb://localhost:27017"))
+	printConfig := flag.Bool("print-config", false, "print the effective configuration as YAML and exit")
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	defer func() {
-		if err = client.Disconnect(context.TODO()); err != nil {
+	if *printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
 			log.Fatal(err)
 		}
-	}()
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("invalid configuration:\n%v", err)
+	}
+	if *printConfig {
+		return
+	}
+
+	var level slog.Level
+	level.UnmarshalText([]byte(cfg.Log.Level))
+	logger, err := logging.NewLogger(os.Stderr, level, cfg.Log.Format)
+	if err != nil {
+		log.Fatal(err)
+	}
+	slog.SetDefault(logger)
+	// Requests are logged by the router, so gin's own output is noise
+	if os.Getenv(gin.EnvGinMode) == "" {
+		gin.SetMode(gin.ReleaseMode)
+	}
 
-	if err = client.Ping(context.TODO(), readpref.Primary()); err != nil {
-		log.Panic(err)
+	if err := run(cfg); err != nil {
+		slog.Error("server failed", "error", err)
+		os.Exit(1)
 	}
+}
 
-	db := client.Database("library")
-	controllers.InitBookController(db)
+// run serves the API until SIGINT or SIGTERM, then stops accepting
+// connections and gives the requests in flight cfg.Server.ShutdownTimeout
+// to finish before canceling them.
+func run(cfg config.Config) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	router := gin.Default()
+	var m *metrics.Metrics
+	if cfg.Metrics {
+		m = metrics.New()
+	}
+	// instrument times the calls to s when metrics are on
+	instrument := func(s store.BookStore) store.BookStore {
+		if m == nil {
+			return s
+		}
+		return m.Store(s)
+	}
 
-	// CORS configuration
-	config := cors.DefaultConfig()
-	config.AllowOrigins = []string{"http://localhost:19000",
-		"http://localhost:19006",
-		"http://192.168.100.34:19006",
-		"*"}
-	config.AllowMethods = []string{"GET", "POST",
-		"PUT", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
-	config.ExposeHeaders = []string{"Content-Length"}
-	config.AllowCredentials = true
+	var bookStore store.BookStore
 
-	router.Use(cors.New(config))
+	// whenReady runs once bookStore can serve requests
+	var ready atomic.Bool
+	whenReady := func() {
+		ready.Store(true)
+		if retention := cfg.Store.TrashRetention.Duration; retention > 0 {
+			go store.PurgeExpired(ctx, bookStore, retention, min(retention, time.Hour))
+		}
+	}
 
-	router.GET("/books", controllers.GetBooks)
-	router.GET("/books/:id", controllers.GetBook)
-	router.POST("/books", controllers.CreateBook)
-	router.PUT("/books/:id", controllers.UpdateBook)
-	router.DELETE("/books/:id", controllers.DeleteBook)
+	switch cfg.Store.Kind {
+	case "memory":
+		slog.Info("using in-memory book store")
+		bookStore = instrument(store.NewMemoryStore())
+		whenReady()
+	case "mongo":
+		clientOpts := options.Client().ApplyURI(cfg.Mongo.URI)
+		if m != nil {
+			clientOpts.SetPoolMonitor(m.PoolMonitor())
+		}
+		client, err := mongo.Connect(clientOpts)
+		if err != nil {
+			return err
+		}
 
-	log.Println("Server running on port 8080")
-	router.Run(":8080")
+		defer func() {
+			disconnectCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout.Duration)
+			defer cancel()
+			if err := client.Disconnect(disconnectCtx); err != nil {
+				slog.Error("failed to disconnect from MongoDB", "error", err)
+			}
+		}()
+
+		mongoStore := store.NewMongoStore(client.Database(cfg.Mongo.Database))
+		bookStore = instrument(mongoStore)
+
+		connect := func(ctx context.Context) error {
+			pingCtx, cancel := context.WithTimeout(ctx, cfg.Mongo.ConnectTimeout.Duration)
+			defer cancel()
+			if err := client.Ping(pingCtx, readpref.Primary()); err != nil {
+				return err
+			}
+			return mongoStore.EnsureIndexes(ctx)
+		}
+		retry := cfg.Mongo.ConnectRetry
+		backoff := store.Backoff{
+			Attempts: retry.Attempts,
+			Initial:  retry.InitialBackoff.Duration,
+			Max:      retry.MaxBackoff.Duration,
+		}
+
+		if cfg.Mongo.StartDegraded {
+			slog.Warn("serving before MongoDB is reachable; book endpoints answer 503 until it is")
+			// Giving up would leave the server answering 503 for good, so
+			// keep trying until MongoDB is up or the server shuts down
+			backoff.Attempts = 0
+			go func() {
+				if err := backoff.Retry(ctx, "connecting to MongoDB", connect); err != nil {
+					return
+				}
+				slog.Info("connected to MongoDB")
+				whenReady()
+			}()
+		} else {
+			if err := backoff.Retry(ctx, "connecting to MongoDB", connect); err != nil {
+				return err
+			}
+			whenReady()
+		}
+	}
+
+	router := controllers.NewRouter(controllers.RouterOptions{
+		Store:          bookStore,
+		Timeout:        cfg.Store.Timeout.Duration,
+		AllowOrigins:   cfg.Server.CORSOrigins,
+		AdminToken:     cfg.Server.AdminToken,
+		IdempotencyTTL: cfg.Server.IdempotencyTTL.Duration,
+		Ready:          ready.Load,
+		HealthTimeout:  cfg.Health.Timeout.Duration,
+		HealthDetail:   cfg.Health.Detail,
+		Metrics:        m,
+	})
+
+	// Request contexts derive from baseCtx, so canceling it stops the
+	// store calls of the requests that outlive the drain
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+	srv := &http.Server{
+		Addr:        cfg.Server.Addr,
+		Handler:     router,
+		BaseContext: func(net.Listener) context.Context { return baseCtx },
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("server listening", "addr", cfg.Server.Addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	// A second signal kills the process without waiting
+	stop()
+	slog.Info("shutting down, waiting for requests in flight", "timeout", cfg.Server.ShutdownTimeout.String())
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout.Duration)
+	defer cancel()
+	if err := srv.Shutdown(drainCtx); err != nil {
+		slog.Warn("canceling requests still in flight after the shutdown timeout", "timeout", cfg.Server.ShutdownTimeout.String())
+		cancelRequests()
+		srv.Close()
+	}
+	slog.Info("server stopped")
+	return nil
 }
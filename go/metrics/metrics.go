@@ -0,0 +1,99 @@
+// Package metrics collects Prometheus metrics about the HTTP API, the book
+// store and the MongoDB connection pool, and serves them in the
+// Prometheus text format.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics holds the server's collectors in a registry of its own, so that
+// several servers can run in one process.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	storeDuration *prometheus.HistogramVec
+	storeErrors   *prometheus.CounterVec
+
+	poolOpen        *prometheus.GaugeVec
+	poolInUse       *prometheus.GaugeVec
+	poolMax         *prometheus.GaugeVec
+	poolWait        *prometheus.HistogramVec
+	poolCheckoutErr *prometheus.CounterVec
+	poolCleared     *prometheus.CounterVec
+}
+
+// New returns Metrics with the Go runtime and process collectors
+// registered as well.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests served, by route template, method and status.",
+		}, []string{"route", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Time taken to serve HTTP requests, by route template, method and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+
+		storeDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "bookstore_operation_duration_seconds",
+			Help:    "Time taken by book store operations, by operation.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"operation"}),
+		storeErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "bookstore_operation_errors_total",
+			Help: "Book store operations that failed, by operation and kind of error.",
+		}, []string{"operation", "error"}),
+
+		poolOpen: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "mongodb_pool_open_connections",
+			Help: "Connections open in the MongoDB connection pool, by server address.",
+		}, []string{"address"}),
+		poolInUse: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "mongodb_pool_checked_out_connections",
+			Help: "Connections checked out of the MongoDB connection pool, by server address.",
+		}, []string{"address"}),
+		poolMax: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "mongodb_pool_max_connections",
+			Help: "Most connections the MongoDB connection pool opens, by server address; 0 is unlimited.",
+		}, []string{"address"}),
+		poolWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "mongodb_pool_checkout_duration_seconds",
+			Help:    "Time taken to check connections out of the MongoDB connection pool, by server address.",
+			Buckets: prometheus.ExponentialBuckets(0.0005, 4, 8),
+		}, []string{"address"}),
+		poolCheckoutErr: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mongodb_pool_checkout_failures_total",
+			Help: "Failed checkouts from the MongoDB connection pool, by server address and reason.",
+		}, []string{"address", "reason"}),
+		poolCleared: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mongodb_pool_cleared_total",
+			Help: "Times the MongoDB connection pool was cleared after an error, by server address.",
+		}, []string{"address"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests, m.httpDuration,
+		m.storeDuration, m.storeErrors,
+		m.poolOpen, m.poolInUse, m.poolMax, m.poolWait, m.poolCheckoutErr, m.poolCleared,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"go.mongodb.org/mongo-driver/v2/event"
+)
+
+// PoolMonitor returns a MongoDB pool monitor that keeps the pool gauges
+// up to date. Set it with options.ClientOptions.SetPoolMonitor.
+func (m *Metrics) PoolMonitor() *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: func(e *event.PoolEvent) {
+			switch e.Type {
+			case event.ConnectionPoolCreated:
+				if e.PoolOptions != nil {
+					m.poolMax.WithLabelValues(e.Address).Set(float64(e.PoolOptions.MaxPoolSize))
+				}
+			case event.ConnectionCreated:
+				m.poolOpen.WithLabelValues(e.Address).Inc()
+			case event.ConnectionClosed:
+				m.poolOpen.WithLabelValues(e.Address).Dec()
+			case event.ConnectionCheckedOut:
+				m.poolInUse.WithLabelValues(e.Address).Inc()
+				m.poolWait.WithLabelValues(e.Address).Observe(e.Duration.Seconds())
+			case event.ConnectionCheckedIn:
+				m.poolInUse.WithLabelValues(e.Address).Dec()
+			case event.ConnectionCheckOutFailed:
+				m.poolCheckoutErr.WithLabelValues(e.Address, e.Reason).Inc()
+			case event.ConnectionPoolCleared:
+				m.poolCleared.WithLabelValues(e.Address).Inc()
+			}
+		},
+	}
+}
@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"go-crud/models"
+	"go-crud/store"
+)
+
+// Store returns s with each operation timed and its failures counted.
+func (m *Metrics) Store(s store.BookStore) store.BookStore {
+	return &instrumentedStore{next: s, m: m}
+}
+
+type instrumentedStore struct {
+	next store.BookStore
+	m    *Metrics
+}
+
+// observe records an operation that started at start and failed with
+// *err, if not nil. Operations defer it with a pointer to their error.
+func (s *instrumentedStore) observe(op string, start time.Time, err *error) {
+	s.m.storeDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+	if *err != nil {
+		s.m.storeErrors.WithLabelValues(op, errorKind(*err)).Inc()
+	}
+}
+
+// errorKind labels a store error by the store package error it wraps.
+func errorKind(err error) string {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return "not_found"
+	case errors.Is(err, store.ErrVersionMismatch):
+		return "version_mismatch"
+	case errors.Is(err, store.ErrConflict):
+		return "conflict"
+	case errors.Is(err, store.ErrUnavailable):
+		return "unavailable"
+	case errors.Is(err, store.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, store.ErrUnsupported):
+		return "unsupported"
+	case errors.Is(err, store.ErrRolledBack):
+		return "rolled_back"
+	}
+	return "other"
+}
+
+func (s *instrumentedStore) List(ctx context.Context, opts store.ListOptions) (res store.ListResult, err error) {
+	defer s.observe("list", time.Now(), &err)
+	return s.next.List(ctx, opts)
+}
+
+func (s *instrumentedStore) Get(ctx context.Context, id bson.ObjectID) (book models.Book, err error) {
+	defer s.observe("get", time.Now(), &err)
+	return s.next.Get(ctx, id)
+}
+
+func (s *instrumentedStore) GetMany(ctx context.Context, ids []bson.ObjectID, fields []string) (books []models.Book, err error) {
+	defer s.observe("get_many", time.Now(), &err)
+	return s.next.GetMany(ctx, ids, fields)
+}
+
+func (s *instrumentedStore) Create(ctx context.Context, book models.Book) (created models.Book, err error) {
+	defer s.observe("create", time.Now(), &err)
+	return s.next.Create(ctx, book)
+}
+
+func (s *instrumentedStore) Update(ctx context.Context, id bson.ObjectID, book models.Book, ifVersion int64) (updated models.Book, err error) {
+	defer s.observe("update", time.Now(), &err)
+	return s.next.Update(ctx, id, book, ifVersion)
+}
+
+func (s *instrumentedStore) Delete(ctx context.Context, id bson.ObjectID, ifVersion int64) (book models.Book, err error) {
+	defer s.observe("delete", time.Now(), &err)
+	return s.next.Delete(ctx, id, ifVersion)
+}
+
+func (s *instrumentedStore) Restore(ctx context.Context, id bson.ObjectID) (book models.Book, err error) {
+	defer s.observe("restore", time.Now(), &err)
+	return s.next.Restore(ctx, id)
+}
+
+func (s *instrumentedStore) Purge(ctx context.Context, id bson.ObjectID) (book models.Book, err error) {
+	defer s.observe("purge", time.Now(), &err)
+	return s.next.Purge(ctx, id)
+}
+
+func (s *instrumentedStore) PurgeTrashed(ctx context.Context, cutoff time.Time) (n int64, err error) {
+	defer s.observe("purge_trashed", time.Now(), &err)
+	return s.next.PurgeTrashed(ctx, cutoff)
+}
+
+func (s *instrumentedStore) Search(ctx context.Context, query string, limit int64) (res store.SearchResult, err error) {
+	defer s.observe("search", time.Now(), &err)
+	return s.next.Search(ctx, query, limit)
+}
+
+func (s *instrumentedStore) BulkWrite(ctx context.Context, ops []store.BulkOp, atomic bool) (results []store.BulkResult, err error) {
+	defer s.observe("bulk_write", time.Now(), &err)
+	return s.next.BulkWrite(ctx, ops, atomic)
+}
+
+func (s *instrumentedStore) AddHistory(ctx context.Context, entries ...models.HistoryEntry) (err error) {
+	defer s.observe("add_history", time.Now(), &err)
+	return s.next.AddHistory(ctx, entries...)
+}
+
+func (s *instrumentedStore) History(ctx context.Context, bookID bson.ObjectID) (entries []models.HistoryEntry, err error) {
+	defer s.observe("history", time.Now(), &err)
+	return s.next.History(ctx, bookID)
+}
+
+func (s *instrumentedStore) ReserveIdempotencyKey(ctx context.Context, key, fingerprint string, expiresAt time.Time) (rec store.IdempotencyRecord, reserved bool, err error) {
+	defer s.observe("reserve_idempotency_key", time.Now(), &err)
+	return s.next.ReserveIdempotencyKey(ctx, key, fingerprint, expiresAt)
+}
+
+func (s *instrumentedStore) CompleteIdempotencyKey(ctx context.Context, key string, response store.StoredResponse) (err error) {
+	defer s.observe("complete_idempotency_key", time.Now(), &err)
+	return s.next.CompleteIdempotencyKey(ctx, key, response)
+}
+
+func (s *instrumentedStore) ReleaseIdempotencyKey(ctx context.Context, key string) (err error) {
+	defer s.observe("release_idempotency_key", time.Now(), &err)
+	return s.next.ReleaseIdempotencyKey(ctx, key)
+}
+
+func (s *instrumentedStore) Ping(ctx context.Context) (err error) {
+	defer s.observe("ping", time.Now(), &err)
+	return s.next.Ping(ctx)
+}
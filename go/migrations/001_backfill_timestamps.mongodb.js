@@ -0,0 +1,19 @@
+// Backfills createdAt and updatedAt on books stored before the server
+// started managing them. createdAt is taken from the ObjectID, which
+// records when the book was inserted, and updatedAt defaults to createdAt.
+// Safe to run more than once.
+use('library')
+db.books.updateMany(
+    { createdAt: { $exists: false } },
+    [{ $set: { createdAt: { $toDate: "$_id" } } }]
+)
+db.books.updateMany(
+    { updatedAt: { $exists: false } },
+    [{ $set: { updatedAt: "$createdAt" } }]
+)
+db.books.createIndex({
+    createdAt: 1
+})
+db.books.createIndex({
+    updatedAt: 1
+})
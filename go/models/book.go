@@ -1,13 +1,28 @@
 package models
 
 import (
-  "go.mongodb.org/mongo-driver/v2/bson"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
+// Book is a library book. The binding tags are checked whenever a book is
+// bound from or patched by a request; notfuture is registered by the
+// controllers package.
+//
+// Version, CreatedAt and UpdatedAt are managed by the store and values sent
+// by clients are ignored: Version starts at 1 and goes up by one on every
+// update, CreatedAt is the time of the insert and UpdatedAt of the last
+// write. Books stored before they were added read as version 0 with no
+// timestamps until migrations/001_backfill_timestamps.mongodb.js is run.
+// DeletedAt is set while the book is in the trash.
 type Book struct {
-	ID     bson.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
-	Title  string             `json:"title" bson:"title"`
-	Author string             `json:"author" bson:"author"`
-	Year   int                `json:"year" bson:"year"`
+	ID        bson.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	Title     string        `json:"title" bson:"title" binding:"required,max=200"`
+	Author    string        `json:"author" bson:"author" binding:"required,max=100"`
+	Year      int           `json:"year" bson:"year" binding:"gte=0,notfuture"`
+	Version   int64         `json:"version" bson:"version"`
+	CreatedAt time.Time     `json:"createdAt,omitzero" bson:"createdAt,omitempty"`
+	UpdatedAt time.Time     `json:"updatedAt,omitzero" bson:"updatedAt,omitempty"`
+	DeletedAt time.Time     `json:"deletedAt,omitzero" bson:"deletedAt,omitempty"`
 }
-
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Actions recorded in the book history.
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+	ActionPurge   = "purge"
+	ActionRevert  = "revert"
+)
+
+// HistoryEntry is the audit record of one change made to a book through
+// the API. Version is the book's version after the change and Book the
+// book as the change left it, which makes the entry the book's revision
+// of that number. A purge leaves no book, so its entry has none and keeps
+// the version of the book it removed.
+type HistoryEntry struct {
+	ID        bson.ObjectID `json:"id" bson:"_id,omitempty"`
+	BookID    bson.ObjectID `json:"bookId" bson:"bookId"`
+	Action    string        `json:"action" bson:"action"`
+	Actor     string        `json:"actor" bson:"actor"`
+	Timestamp time.Time     `json:"timestamp" bson:"timestamp"`
+	Version   int64         `json:"version" bson:"version"`
+	Changes   []FieldChange `json:"changes" bson:"changes"`
+	Book      Book          `json:"book,omitzero" bson:"book,omitempty"`
+}
+
+// FieldChange is the value of one book field, by JSON name, before and
+// after a change. A nil value means the field was absent.
+type FieldChange struct {
+	Field  string `json:"field" bson:"field"`
+	Before any    `json:"before,omitempty" bson:"before,omitempty"`
+	After  any    `json:"after,omitempty" bson:"after,omitempty"`
+}
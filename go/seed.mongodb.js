@@ -1,25 +1,51 @@
 use('library')
+const now = new Date()
 db.books.drop()
 db.books.insertMany(
     [
         {
             title: "The Go Programming Language",
             author: "Alan A. A. Donovan",
-            year: 2016
+            year: 2016,
+            version: 1,
+            createdAt: now,
+            updatedAt: now
         },
         {
             title: "MongoDB: The Definitive Guide",
             author: "Kristina Chodorow",
-            year: 2013
+            year: 2013,
+            version: 1,
+            createdAt: now,
+            updatedAt: now
         },
         {
             title: "Clean Code",
             author: "Robert C. Martin",
-            year: 2008
+            year: 2008,
+            version: 1,
+            createdAt: now,
+            updatedAt: now
         }
     ]
 )
 db.books.createIndex({
     title: 1
 })
+db.books.createIndex({
+    createdAt: 1
+})
+db.books.createIndex({
+    updatedAt: 1
+})
+db.books.createIndex(
+    {
+        title: "text",
+        author: "text"
+    },
+    {
+        name: "title_author_text",
+        weights: { title: 2, author: 1 }
+    }
+)
 db.books.find().pretty()
\ No newline at end of file
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"go-crud/models"
+)
+
+// Kinds of BulkOp.
+const (
+	BulkCreate = "create"
+	BulkUpdate = "update"
+	BulkDelete = "delete"
+)
+
+// ErrRolledBack is the error of the operations of an atomic BulkWrite that
+// succeeded but were undone because another one failed.
+var ErrRolledBack = errors.New("rolled back because another operation failed")
+
+// BulkOp is one write of a BulkWrite. ID is unused for creates and Book for
+// deletes. IfVersion conditions updates and deletes like it does Update and
+// Delete.
+type BulkOp struct {
+	Kind      string
+	ID        bson.ObjectID
+	Book      models.Book
+	IfVersion int64
+}
+
+// BulkResult is the outcome of one BulkOp: the book as it was before and
+// after the write, or the error that made the operation fail.
+type BulkResult struct {
+	Before models.Book
+	Book   models.Book
+	Err    error
+}
+
+// created returns book as Create stores it, written at ts.
+func created(book models.Book, ts time.Time) models.Book {
+	book.Version = 1
+	book.CreatedAt = ts
+	book.UpdatedAt = ts
+	book.DeletedAt = time.Time{}
+	return book
+}
+
+// updated returns existing with the fields Update writes taken from book,
+// written at ts.
+func updated(existing, book models.Book, ts time.Time) models.Book {
+	existing.Title = book.Title
+	existing.Author = book.Author
+	existing.Year = book.Year
+	existing.Version++
+	existing.UpdatedAt = ts
+	return existing
+}
+
+// trashed returns existing as Delete leaves it, written at ts.
+func trashed(existing models.Book, ts time.Time) models.Book {
+	existing.Version++
+	existing.UpdatedAt = ts
+	existing.DeletedAt = ts
+	return existing
+}
+
+// rollBack reports whether any operation failed and, if one did, replaces
+// the results of the others with ErrRolledBack.
+func rollBack(results []BulkResult) bool {
+	failed := false
+	for _, r := range results {
+		if r.Err != nil {
+			failed = true
+			break
+		}
+	}
+	if !failed {
+		return false
+	}
+
+	for i := range results {
+		if results[i].Err == nil {
+			results[i] = BulkResult{Err: ErrRolledBack}
+		}
+	}
+	return true
+}
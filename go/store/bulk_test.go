@@ -0,0 +1,27 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"go-crud/models"
+)
+
+func TestCreatedIgnoresClientFields(t *testing.T) {
+	ts := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	sent := models.Book{
+		Title:     "Dune",
+		Author:    "Frank Herbert",
+		Year:      1965,
+		Version:   7,
+		CreatedAt: time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC),
+		DeletedAt: time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	got := created(sent, ts)
+	want := models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965, Version: 1, CreatedAt: ts, UpdatedAt: ts}
+	if got != want {
+		t.Errorf("created = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,20 @@
+package store
+
+import "time"
+
+// IdempotencyRecord is a request claimed under an Idempotency-Key. Response
+// is nil while the request is in progress.
+type IdempotencyRecord struct {
+	Key         string          `bson:"_id"`
+	Fingerprint string          `bson:"fingerprint"`
+	Response    *StoredResponse `bson:"response,omitempty"`
+	ExpiresAt   time.Time       `bson:"expiresAt"`
+}
+
+// StoredResponse is a response kept to be replayed for repeats of a
+// request.
+type StoredResponse struct {
+	Status int               `bson:"status"`
+	Header map[string]string `bson:"header"`
+	Body   []byte            `bson:"body"`
+}
@@ -0,0 +1,386 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"go-crud/models"
+)
+
+// MemoryStore keeps books in process memory. It is safe for concurrent use
+// and is meant for demos and tests that should not need a database.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	books   map[bson.ObjectID]models.Book
+	order   []bson.ObjectID
+	history []models.HistoryEntry
+	keys    map[string]IdempotencyRecord
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		books: make(map[bson.ObjectID]models.Book),
+		keys:  make(map[string]IdempotencyRecord),
+	}
+}
+
+func (s *MemoryStore) List(ctx context.Context, opts ListOptions) (ListResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	books := make([]models.Book, 0, len(s.order))
+	for _, id := range s.order {
+		book := s.books[id]
+		if book.DeletedAt.IsZero() != opts.Trashed && matchesAll(book, opts.Filters) {
+			books = append(books, book)
+		}
+	}
+	slices.SortFunc(books, func(a, b models.Book) int {
+		return compareBooks(a, b, opts.Sort)
+	})
+
+	total := int64(len(books))
+	start := min(max(opts.Offset, 0), total)
+	if opts.After != nil {
+		start = int64(sort.Search(len(books), func(i int) bool {
+			return compareKeys(SortKey(books[i], opts.Sort), opts.After, opts.Sort) > 0
+		}))
+	}
+	end := total
+	if opts.Limit > 0 {
+		end = min(start+opts.Limit, total)
+	}
+
+	page := books[start:end]
+	for i := range page {
+		page[i] = project(page[i], opts.Fields)
+	}
+	return ListResult{Books: page, Total: total}, nil
+}
+
+func matchesAll(book models.Book, filters []Filter) bool {
+	for _, f := range filters {
+		if !f.matches(book) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id bson.ObjectID) (models.Book, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	book, ok := s.live(id)
+	if !ok {
+		return models.Book{}, ErrNotFound
+	}
+	return book, nil
+}
+
+func (s *MemoryStore) GetMany(ctx context.Context, ids []bson.ObjectID, fields []string) ([]models.Book, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	books := []models.Book{}
+	for _, id := range ids {
+		if book, ok := s.live(id); ok {
+			books = append(books, project(book, fields))
+		}
+	}
+	return books, nil
+}
+
+// live returns the book with the given ID unless it is missing or in the
+// trash. Callers must hold s.mu.
+func (s *MemoryStore) live(id bson.ObjectID) (models.Book, bool) {
+	book, ok := s.books[id]
+	return book, ok && book.DeletedAt.IsZero()
+}
+
+func (s *MemoryStore) Create(ctx context.Context, book models.Book) (models.Book, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.create(book), nil
+}
+
+func (s *MemoryStore) Update(ctx context.Context, id bson.ObjectID, book models.Book, ifVersion int64) (models.Book, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, book, err := s.update(id, book, ifVersion)
+	return book, err
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, id bson.ObjectID, ifVersion int64) (models.Book, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, book, err := s.delete(id, ifVersion)
+	return book, err
+}
+
+// create, update and delete implement the writes of the same names for
+// callers holding s.mu. update and delete also return the book as it was.
+func (s *MemoryStore) create(book models.Book) models.Book {
+	book.ID = bson.NewObjectID()
+	book = created(book, now())
+	s.books[book.ID] = book
+	s.order = append(s.order, book.ID)
+	return book
+}
+
+func (s *MemoryStore) update(id bson.ObjectID, book models.Book, ifVersion int64) (before, after models.Book, err error) {
+	existing, err := s.writable(id, ifVersion)
+	if err != nil {
+		return models.Book{}, models.Book{}, err
+	}
+	after = updated(existing, book, now())
+	s.books[id] = after
+	return existing, after, nil
+}
+
+func (s *MemoryStore) delete(id bson.ObjectID, ifVersion int64) (before, after models.Book, err error) {
+	existing, err := s.writable(id, ifVersion)
+	if err != nil {
+		return models.Book{}, models.Book{}, err
+	}
+	after = trashed(existing, now())
+	s.books[id] = after
+	return existing, after, nil
+}
+
+// writable returns the live book with the given ID if it is at version
+// ifVersion. Callers must hold s.mu.
+func (s *MemoryStore) writable(id bson.ObjectID, ifVersion int64) (models.Book, error) {
+	existing, ok := s.live(id)
+	if !ok {
+		return models.Book{}, ErrNotFound
+	}
+	if ifVersion != AnyVersion && existing.Version != ifVersion {
+		return models.Book{}, ErrVersionMismatch
+	}
+	return existing, nil
+}
+
+// BulkWrite applies the operations in order under a single lock. In atomic
+// mode the store is put back as it was if any of them fails.
+func (s *MemoryStore) BulkWrite(ctx context.Context, ops []BulkOp, atomic bool) ([]BulkResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var books map[bson.ObjectID]models.Book
+	var order []bson.ObjectID
+	if atomic {
+		books, order = maps.Clone(s.books), slices.Clone(s.order)
+	}
+
+	results := make([]BulkResult, len(ops))
+	for i, op := range ops {
+		r := &results[i]
+		switch op.Kind {
+		case BulkCreate:
+			r.Book = s.create(op.Book)
+		case BulkUpdate:
+			r.Before, r.Book, r.Err = s.update(op.ID, op.Book, op.IfVersion)
+		case BulkDelete:
+			r.Before, r.Book, r.Err = s.delete(op.ID, op.IfVersion)
+		default:
+			r.Err = fmt.Errorf("unknown bulk operation %q", op.Kind)
+		}
+	}
+
+	if atomic && rollBack(results) {
+		s.books, s.order = books, order
+	}
+	return results, nil
+}
+
+func (s *MemoryStore) Restore(ctx context.Context, id bson.ObjectID) (models.Book, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	book, ok := s.books[id]
+	if !ok || book.DeletedAt.IsZero() {
+		return models.Book{}, ErrNotFound
+	}
+
+	book.Version++
+	book.UpdatedAt = now()
+	book.DeletedAt = time.Time{}
+	s.books[id] = book
+	return book, nil
+}
+
+func (s *MemoryStore) Purge(ctx context.Context, id bson.ObjectID) (models.Book, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	book, ok := s.books[id]
+	if !ok {
+		return models.Book{}, ErrNotFound
+	}
+	s.remove(id)
+	return book, nil
+}
+
+func (s *MemoryStore) PurgeTrashed(ctx context.Context, cutoff time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var purged int64
+	for _, book := range s.books {
+		if !book.DeletedAt.IsZero() && book.DeletedAt.Before(cutoff) {
+			s.remove(book.ID)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// remove deletes a book from the store. Callers must hold s.mu.
+func (s *MemoryStore) remove(id bson.ObjectID) {
+	delete(s.books, id)
+	for i, existing := range s.order {
+		if existing == id {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// Search scores each book by how many times the query words occur as whole
+// words of its title and author, counting title matches twice like the
+// Mongo text index weights. Like the text index it ignores case and
+// punctuation, so "dun" does not find "Dune"; unlike it, words are not
+// stemmed, so "dunes" does not find it either, and quoted phrases and
+// negated words count as plain words.
+func (s *MemoryStore) Search(ctx context.Context, query string, limit int64) (SearchResult, error) {
+	terms := searchWords(query)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	hits := []SearchHit{}
+	for _, id := range s.order {
+		book, ok := s.live(id)
+		if !ok {
+			continue
+		}
+		title, author := searchWords(book.Title), searchWords(book.Author)
+
+		var score float64
+		for _, term := range terms {
+			score += 2*float64(countWord(title, term)) + float64(countWord(author, term))
+		}
+		if score > 0 {
+			hits = append(hits, SearchHit{Book: book, Score: score})
+		}
+	}
+
+	slices.SortStableFunc(hits, func(a, b SearchHit) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+	total := int64(len(hits))
+	if limit > 0 && total > limit {
+		hits = hits[:limit]
+	}
+	return SearchResult{Hits: hits, Total: total}, nil
+}
+
+// searchWords splits text into lower-case words, dropping punctuation.
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+func countWord(words []string, word string) int {
+	n := 0
+	for _, w := range words {
+		if w == word {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *MemoryStore) AddHistory(ctx context.Context, entries ...models.HistoryEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, entry := range entries {
+		entry.ID = bson.NewObjectID()
+		s.history = append(s.history, entry)
+	}
+	return nil
+}
+
+func (s *MemoryStore) History(ctx context.Context, bookID bson.ObjectID) ([]models.HistoryEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := []models.HistoryEntry{}
+	for _, entry := range s.history {
+		if entry.BookID == bookID {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func (s *MemoryStore) ReserveIdempotencyKey(ctx context.Context, key, fingerprint string, expiresAt time.Time) (IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ts := now()
+	maps.DeleteFunc(s.keys, func(_ string, rec IdempotencyRecord) bool {
+		return !rec.ExpiresAt.After(ts)
+	})
+
+	if rec, ok := s.keys[key]; ok {
+		return rec, false, nil
+	}
+	rec := IdempotencyRecord{Key: key, Fingerprint: fingerprint, ExpiresAt: expiresAt}
+	s.keys[key] = rec
+	return rec, true, nil
+}
+
+func (s *MemoryStore) CompleteIdempotencyKey(ctx context.Context, key string, response StoredResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.keys[key]
+	if !ok {
+		return ErrNotFound
+	}
+	rec.Response = &response
+	s.keys[key] = rec
+	return nil
+}
+
+func (s *MemoryStore) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rec, ok := s.keys[key]; ok && rec.Response == nil {
+		delete(s.keys, key)
+	}
+	return nil
+}
+
+// Ping always succeeds, as the books are in process memory.
+func (s *MemoryStore) Ping(ctx context.Context) error {
+	return nil
+}
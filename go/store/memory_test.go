@@ -0,0 +1,316 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"go-crud/models"
+)
+
+// seedBooks fills a new MemoryStore with a few books, created in the order
+// given.
+func seedBooks(t *testing.T) *MemoryStore {
+	t.Helper()
+	s := NewMemoryStore()
+	for _, book := range []models.Book{
+		{Title: "Dune", Author: "Frank Herbert", Year: 1965},
+		{Title: "Emma", Author: "Jane Austen", Year: 1815},
+		{Title: "Hyperion", Author: "Dan Simmons", Year: 1989},
+		{Title: "Persuasion", Author: "Jane Austen", Year: 1817},
+		{Title: "Neuromancer", Author: "William Gibson", Year: 1984},
+	} {
+		if _, err := s.Create(context.Background(), book); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+func titles(books []models.Book) []string {
+	out := make([]string, len(books))
+	for i, b := range books {
+		out[i] = b.Title
+	}
+	return out
+}
+
+func TestMemoryStoreListFilters(t *testing.T) {
+	s := seedBooks(t)
+
+	tests := []struct {
+		name    string
+		filters []Filter
+		want    []string
+	}{
+		{"none", nil, []string{"Dune", "Emma", "Hyperion", "Persuasion", "Neuromancer"}},
+		{"equal", []Filter{{Field: "author", Op: OpEq, Value: "Jane Austen"}}, []string{"Emma", "Persuasion"}},
+		{"range", []Filter{{Field: "year", Op: OpGte, Value: 1965}, {Field: "year", Op: OpLt, Value: 1989}}, []string{"Dune", "Neuromancer"}},
+		{"equal and range", []Filter{{Field: "author", Op: OpEq, Value: "Jane Austen"}, {Field: "year", Op: OpGt, Value: 1815}}, []string{"Persuasion"}},
+		{"no match", []Filter{{Field: "title", Op: OpEq, Value: "Ulysses"}}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.List(context.Background(), ListOptions{Filters: tt.filters})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := titles(res.Books); !slices.Equal(got, tt.want) {
+				t.Errorf("List = %v, want %v", got, tt.want)
+			}
+			if res.Total != int64(len(tt.want)) {
+				t.Errorf("Total = %d, want %d", res.Total, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestMemoryStoreListSortAndPage(t *testing.T) {
+	s := seedBooks(t)
+
+	tests := []struct {
+		name string
+		opts ListOptions
+		want []string
+	}{
+		{"by year", ListOptions{Sort: []SortField{{Field: "year"}}}, []string{"Emma", "Persuasion", "Dune", "Neuromancer", "Hyperion"}},
+		{"by author then year descending", ListOptions{Sort: []SortField{{Field: "author"}, {Field: "year", Desc: true}}}, []string{"Hyperion", "Dune", "Persuasion", "Emma", "Neuromancer"}},
+		{"offset and limit", ListOptions{Sort: []SortField{{Field: "title"}}, Offset: 1, Limit: 2}, []string{"Emma", "Hyperion"}},
+		{"offset past the end", ListOptions{Offset: 10, Limit: 2}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.List(context.Background(), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := titles(res.Books); !slices.Equal(got, tt.want) {
+				t.Errorf("List = %v, want %v", got, tt.want)
+			}
+			if res.Total != 5 {
+				t.Errorf("Total = %d, want 5", res.Total)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreListAfter(t *testing.T) {
+	s := seedBooks(t)
+	// Books sharing an author test that the _id tiebreaker keeps pages
+	// from skipping or repeating books
+	sortFields := []SortField{{Field: "author", Desc: true}}
+
+	var got []string
+	opts := ListOptions{Sort: sortFields, Limit: 2}
+	for range 5 {
+		res, err := s.List(context.Background(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Books) == 0 {
+			break
+		}
+		got = append(got, titles(res.Books)...)
+		opts.After = SortKey(res.Books[len(res.Books)-1], sortFields)
+	}
+
+	want := []string{"Neuromancer", "Emma", "Persuasion", "Dune", "Hyperion"}
+	if !slices.Equal(got, want) {
+		t.Errorf("pages = %v, want %v", got, want)
+	}
+}
+
+func TestMemoryStoreTrash(t *testing.T) {
+	ctx := context.Background()
+	s := seedBooks(t)
+	res, _ := s.List(ctx, ListOptions{Filters: []Filter{{Field: "title", Op: OpEq, Value: "Emma"}}})
+	emma := res.Books[0]
+
+	deleted, err := s.Delete(ctx, emma.ID, emma.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted.DeletedAt.IsZero() || deleted.Version != emma.Version+1 {
+		t.Errorf("Delete = %+v, want it trashed at the next version", deleted)
+	}
+
+	live, _ := s.List(ctx, ListOptions{})
+	if slices.Contains(titles(live.Books), "Emma") || live.Total != 4 {
+		t.Errorf("live books = %v, want Emma left out", titles(live.Books))
+	}
+	trash, _ := s.List(ctx, ListOptions{Trashed: true})
+	if got := titles(trash.Books); !slices.Equal(got, []string{"Emma"}) {
+		t.Errorf("trashed books = %v, want [Emma]", got)
+	}
+
+	if _, err := s.Get(ctx, emma.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a trashed book: error = %v, want %v", err, ErrNotFound)
+	}
+	if _, err := s.Update(ctx, emma.ID, emma, AnyVersion); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Update of a trashed book: error = %v, want %v", err, ErrNotFound)
+	}
+	if books, _ := s.GetMany(ctx, []bson.ObjectID{emma.ID}, nil); len(books) != 0 {
+		t.Errorf("GetMany of a trashed book = %v, want none", books)
+	}
+
+	restored, err := s.Restore(ctx, emma.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !restored.DeletedAt.IsZero() {
+		t.Errorf("Restore = %+v, want it out of the trash", restored)
+	}
+	if _, err := s.Get(ctx, emma.ID); err != nil {
+		t.Errorf("Get of a restored book: %v", err)
+	}
+}
+
+func TestMemoryStoreConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	book, err := s.Create(ctx, models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const writers = 20
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	applied := 0
+	for i := range writers {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := s.Create(ctx, models.Book{Title: "Copy", Author: "Frank Herbert", Year: 1965 + i}); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			// Every writer expects version 1, so only one may succeed
+			_, err := s.Update(ctx, book.ID, models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1966}, 1)
+			switch {
+			case err == nil:
+				mu.Lock()
+				applied++
+				mu.Unlock()
+			case !errors.Is(err, ErrVersionMismatch):
+				t.Error(err)
+			}
+			s.List(ctx, ListOptions{Sort: []SortField{{Field: "year"}}})
+		}()
+	}
+	wg.Wait()
+
+	if applied != 1 {
+		t.Errorf("%d conditional updates applied, want 1", applied)
+	}
+	res, _ := s.List(ctx, ListOptions{})
+	if res.Total != writers+1 {
+		t.Errorf("Total = %d, want %d", res.Total, writers+1)
+	}
+	got, _ := s.Get(ctx, book.ID)
+	if got.Version != 2 {
+		t.Errorf("Version = %d, want 2", got.Version)
+	}
+}
+
+func TestMemoryStoreSearch(t *testing.T) {
+	s := seedBooks(t)
+
+	tests := []struct {
+		query string
+		limit int64
+		want  []string
+		total int64
+	}{
+		{"dune", 10, []string{"Dune"}, 1},
+		{"DUNE!", 10, []string{"Dune"}, 1},
+		// Whole words only, like the Mongo text index
+		{"Dun", 10, []string{}, 0},
+		{"austen", 10, []string{"Emma", "Persuasion"}, 2},
+		{"austen", 1, []string{"Emma"}, 2},
+		// Title matches count twice
+		{"emma jane", 10, []string{"Emma", "Persuasion"}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			res, err := s.Search(context.Background(), tt.query, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, hit := range res.Hits {
+				got = append(got, hit.Book.Title)
+			}
+			if !slices.Equal(got, tt.want) || res.Total != tt.total {
+				t.Errorf("Search(%q) = %v with total %d, want %v with total %d", tt.query, got, res.Total, tt.want, tt.total)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreBulkWriteAtomic(t *testing.T) {
+	ctx := context.Background()
+	s := seedBooks(t)
+	before, _ := s.List(ctx, ListOptions{})
+	dune := before.Books[0]
+
+	results, err := s.BulkWrite(ctx, []BulkOp{
+		{Kind: BulkCreate, Book: models.Book{Title: "Ulysses", Author: "James Joyce", Year: 1922}},
+		{Kind: BulkUpdate, ID: dune.ID, Book: models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1966}, IfVersion: dune.Version},
+		{Kind: BulkDelete, ID: dune.ID, IfVersion: dune.Version},
+	}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The delete expects the version the update replaced
+	wantErrs := []error{ErrRolledBack, ErrRolledBack, ErrVersionMismatch}
+	for i, want := range wantErrs {
+		if !errors.Is(results[i].Err, want) {
+			t.Errorf("op %d: error = %v, want %v", i, results[i].Err, want)
+		}
+	}
+
+	after, _ := s.List(ctx, ListOptions{})
+	if !slices.Equal(after.Books, before.Books) {
+		t.Errorf("books after a rolled back BulkWrite = %v, want %v", titles(after.Books), titles(before.Books))
+	}
+}
+
+func TestMemoryStoreBulkWriteSameBook(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	book, err := s.Create(ctx, models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each op expects the version the one before it leaves
+	results, err := s.BulkWrite(ctx, []BulkOp{
+		{Kind: BulkUpdate, ID: book.ID, Book: models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1966}, IfVersion: 1},
+		{Kind: BulkUpdate, ID: book.ID, Book: models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1967}, IfVersion: 2},
+		{Kind: BulkDelete, ID: book.ID, IfVersion: 3},
+		{Kind: BulkUpdate, ID: book.ID, Book: models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1968}, IfVersion: AnyVersion},
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, r := range results[:3] {
+		if r.Err != nil || r.Before.Version != int64(i+1) || r.Book.Version != int64(i+2) {
+			t.Errorf("op %d = %+v, want version %d replaced by %d", i, r, i+1, i+2)
+		}
+	}
+	if !errors.Is(results[3].Err, ErrNotFound) {
+		t.Errorf("update of the deleted book: error = %v, want %v", results[3].Err, ErrNotFound)
+	}
+	if results[2].Before.Year != 1967 || results[2].Book.DeletedAt.IsZero() {
+		t.Errorf("delete = %+v, want the 1967 book trashed", results[2])
+	}
+}
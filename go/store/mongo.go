@@ -0,0 +1,678 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+	"go.mongodb.org/mongo-driver/v2/x/mongo/driver/topology"
+
+	"go-crud/models"
+)
+
+// MongoStore keeps books in a MongoDB collection, with their history and
+// idempotency keys in others.
+type MongoStore struct {
+	collection  *mongo.Collection
+	history     *mongo.Collection
+	idempotency *mongo.Collection
+}
+
+func NewMongoStore(db *mongo.Database) *MongoStore {
+	return &MongoStore{
+		collection:  db.Collection("books"),
+		history:     db.Collection("book_history"),
+		idempotency: db.Collection("idempotency_keys"),
+	}
+}
+
+// Ping checks that the primary can be reached, as writes need it.
+func (s *MongoStore) Ping(ctx context.Context) error {
+	return mongoError(s.collection.Database().Client().Ping(ctx, readpref.Primary()))
+}
+
+// EnsureIndexes creates the indexes the store's queries rely on, including
+// the text index used by Search. It is safe to call on every startup.
+func (s *MongoStore) EnsureIndexes(ctx context.Context) error {
+	_, err := s.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "title", Value: 1}}},
+		{Keys: bson.D{{Key: "createdAt", Value: 1}}},
+		{Keys: bson.D{{Key: "updatedAt", Value: 1}}},
+		{Keys: bson.D{{Key: "deletedAt", Value: 1}}},
+		{
+			Keys: bson.D{{Key: "title", Value: "text"}, {Key: "author", Value: "text"}},
+			Options: options.Index().
+				SetName("title_author_text").
+				SetWeights(bson.D{{Key: "title", Value: 2}, {Key: "author", Value: 1}}),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.history.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "bookId", Value: 1}, {Key: "timestamp", Value: 1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Let the server remove expired idempotency keys
+	_, err = s.idempotency.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+func (s *MongoStore) List(ctx context.Context, opts ListOptions) (ListResult, error) {
+	filter := bson.M{"deletedAt": nil}
+	if opts.Trashed {
+		filter = bson.M{"deletedAt": bson.M{"$ne": nil}}
+	}
+	// Keep filters on deletedAt, such as deletedAt_lt in the trash,
+	// alongside the live or trashed condition rather than replacing it
+	if len(opts.Filters) > 0 {
+		filter = bson.M{"$and": bson.A{mongoFilter(opts.Filters), filter}}
+	}
+
+	total, err := s.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return ListResult{}, mongoError(err)
+	}
+
+	findOpts := options.Find().SetSort(mongoSort(opts.Sort)).SetSkip(opts.Offset)
+	if opts.Limit > 0 {
+		findOpts.SetLimit(opts.Limit)
+	}
+	if opts.Fields != nil {
+		findOpts.SetProjection(mongoProjection(opts.Fields))
+	}
+
+	pageFilter := filter
+	if opts.After != nil {
+		pageFilter = bson.M{"$and": bson.A{filter, mongoAfter(opts.Sort, opts.After)}}
+	}
+
+	cursor, err := s.collection.Find(ctx, pageFilter, findOpts)
+	if err != nil {
+		return ListResult{}, mongoError(err)
+	}
+	defer cursor.Close(ctx)
+
+	books := []models.Book{}
+	if err := cursor.All(ctx, &books); err != nil {
+		return ListResult{}, mongoError(err)
+	}
+	return ListResult{Books: books, Total: total}, nil
+}
+
+// mongoFilter translates filters into a query document. Filters on the
+// same field are merged, so year_gte and year_lte form a single range and
+// title=X&title_gte=A needs both to match.
+func mongoFilter(filters []Filter) bson.M {
+	query := bson.M{}
+	for _, f := range filters {
+		cond, ok := query[f.Field].(bson.M)
+		if !ok {
+			cond = bson.M{}
+			query[f.Field] = cond
+		}
+		cond["$"+f.Op] = f.Value
+	}
+	return query
+}
+
+// mongoProjection includes the given stored fields, and always _id.
+func mongoProjection(fields []string) bson.M {
+	projection := bson.M{}
+	for _, f := range fields {
+		projection[f] = 1
+	}
+	return projection
+}
+
+// mongoSort translates sort fields into a sort document that always
+// includes _id so that pages are stable.
+func mongoSort(sortFields []SortField) bson.D {
+	sort := bson.D{}
+	for _, f := range StableSort(sortFields) {
+		dir := 1
+		if f.Desc {
+			dir = -1
+		}
+		sort = append(sort, bson.E{Key: f.Field, Value: dir})
+	}
+	return sort
+}
+
+// mongoAfter matches the books that sort strictly after key: for sort
+// fields a, b it is {$or: [{a: {$gt: ka}}, {a: ka, b: {$gt: kb}}]}.
+func mongoAfter(sortFields []SortField, key []any) bson.M {
+	stable := StableSort(sortFields)
+	or := bson.A{}
+	for i, f := range stable {
+		cond := bson.M{}
+		for j := range i {
+			cond[stable[j].Field] = key[j]
+		}
+		op := "$gt"
+		if f.Desc {
+			op = "$lt"
+		}
+		cond[f.Field] = bson.M{op: key[i]}
+		or = append(or, cond)
+	}
+	return bson.M{"$or": or}
+}
+
+func (s *MongoStore) Get(ctx context.Context, id bson.ObjectID) (models.Book, error) {
+	var book models.Book
+	err := s.collection.FindOne(ctx, bson.M{"_id": id, "deletedAt": nil}).Decode(&book)
+	if err != nil {
+		return models.Book{}, mongoError(err)
+	}
+	return book, nil
+}
+
+func (s *MongoStore) GetMany(ctx context.Context, ids []bson.ObjectID, fields []string) ([]models.Book, error) {
+	findOpts := options.Find()
+	if fields != nil {
+		findOpts.SetProjection(mongoProjection(fields))
+	}
+
+	cursor, err := s.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}, "deletedAt": nil}, findOpts)
+	if err != nil {
+		return nil, mongoError(err)
+	}
+	defer cursor.Close(ctx)
+
+	books := []models.Book{}
+	if err := cursor.All(ctx, &books); err != nil {
+		return nil, mongoError(err)
+	}
+	return books, nil
+}
+
+func (s *MongoStore) Create(ctx context.Context, book models.Book) (models.Book, error) {
+	book.ID = bson.ObjectID{}
+	book = created(book, now())
+	res, err := s.collection.InsertOne(ctx, book)
+	if err != nil {
+		return models.Book{}, mongoError(err)
+	}
+
+	book.ID = res.InsertedID.(bson.ObjectID)
+	return book, nil
+}
+
+func (s *MongoStore) Update(ctx context.Context, id bson.ObjectID, book models.Book, ifVersion int64) (models.Book, error) {
+	ts := now()
+	before, err := s.modify(ctx, id, ifVersion, updateDoc(book, ts))
+	if err != nil {
+		return models.Book{}, err
+	}
+	return updated(before, book, ts), nil
+}
+
+func (s *MongoStore) Delete(ctx context.Context, id bson.ObjectID, ifVersion int64) (models.Book, error) {
+	ts := now()
+	before, err := s.modify(ctx, id, ifVersion, trashDoc(ts))
+	if err != nil {
+		return models.Book{}, err
+	}
+	return trashed(before, ts), nil
+}
+
+func (s *MongoStore) Restore(ctx context.Context, id bson.ObjectID) (models.Book, error) {
+	update := bson.M{
+		"$set":   bson.M{"updatedAt": now()},
+		"$unset": bson.M{"deletedAt": ""},
+		"$inc":   bson.M{"version": 1},
+	}
+
+	var restored models.Book
+	err := s.collection.FindOneAndUpdate(ctx, bson.M{"_id": id, "deletedAt": bson.M{"$ne": nil}}, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&restored)
+	if err != nil {
+		return models.Book{}, mongoError(err)
+	}
+	return restored, nil
+}
+
+func (s *MongoStore) Purge(ctx context.Context, id bson.ObjectID) (models.Book, error) {
+	var purged models.Book
+	err := s.collection.FindOneAndDelete(ctx, bson.M{"_id": id}).Decode(&purged)
+	if err != nil {
+		return models.Book{}, mongoError(err)
+	}
+	return purged, nil
+}
+
+func (s *MongoStore) PurgeTrashed(ctx context.Context, cutoff time.Time) (int64, error) {
+	res, err := s.collection.DeleteMany(ctx, bson.M{"deletedAt": bson.M{"$lt": cutoff}})
+	if err != nil {
+		return 0, mongoError(err)
+	}
+	return res.DeletedCount, nil
+}
+
+// versionFilter matches the live book with the given ID at version
+// ifVersion. Version 0 also matches books stored before they had a version
+// field.
+func versionFilter(id bson.ObjectID, ifVersion int64) bson.M {
+	filter := bson.M{"_id": id, "deletedAt": nil}
+	switch {
+	case ifVersion == 0:
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	case ifVersion > 0:
+		filter["version"] = ifVersion
+	}
+	return filter
+}
+
+// missOrMismatch explains why a conditional write matched nothing: either
+// the book is gone or it is at another version.
+func (s *MongoStore) missOrMismatch(ctx context.Context, id bson.ObjectID) error {
+	if _, err := s.Get(ctx, id); err != nil {
+		return err
+	}
+	return ErrVersionMismatch
+}
+
+func (s *MongoStore) Search(ctx context.Context, query string, limit int64) (SearchResult, error) {
+	filter := bson.M{"$text": bson.M{"$search": query}, "deletedAt": nil}
+	total, err := s.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return SearchResult{}, mongoError(err)
+	}
+
+	score := bson.M{"$meta": "textScore"}
+	findOpts := options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}}).
+		SetLimit(limit)
+
+	cursor, err := s.collection.Find(ctx, filter, findOpts)
+	if err != nil {
+		return SearchResult{}, mongoError(err)
+	}
+	defer cursor.Close(ctx)
+
+	var docs []struct {
+		models.Book `bson:",inline"`
+		Score       float64 `bson:"score"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return SearchResult{}, mongoError(err)
+	}
+
+	hits := make([]SearchHit, len(docs))
+	for i, doc := range docs {
+		hits[i] = SearchHit{Book: doc.Book, Score: doc.Score}
+	}
+	return SearchResult{Hits: hits, Total: total}, nil
+}
+
+// bulkWorkers bounds the books a non-atomic BulkWrite writes at once.
+const bulkWorkers = 8
+
+// BulkWrite writes each op with a call of its own, so that its outcome is
+// exactly known. Ops on different books run in parallel and those on the
+// same book in order. In atomic mode the ops are instead sent as one
+// ordered BulkWrite inside a transaction, which needs a replica set.
+func (s *MongoStore) BulkWrite(ctx context.Context, ops []BulkOp, atomic bool) ([]BulkResult, error) {
+	if !atomic {
+		return s.bulkWriteEach(ctx, ops), nil
+	}
+
+	session, err := s.collection.Database().Client().StartSession()
+	if err != nil {
+		return nil, mongoError(err)
+	}
+	defer session.EndSession(ctx)
+
+	var results []BulkResult
+	_, err = session.WithTransaction(ctx, func(ctx context.Context) (any, error) {
+		var err error
+		results, err = s.bulkWriteOrdered(ctx, ops)
+		if err == nil && rollBack(results) {
+			// Abort the transaction
+			err = ErrRolledBack
+		}
+		return nil, err
+	})
+	if errors.Is(err, ErrRolledBack) {
+		return results, nil
+	}
+	if err != nil {
+		return nil, mongoError(err)
+	}
+	return results, nil
+}
+
+// bulkWriteEach runs the ops of a non-atomic BulkWrite on bulkWorkers
+// goroutines, giving each book's ops to a single one.
+func (s *MongoStore) bulkWriteEach(ctx context.Context, ops []BulkOp) []BulkResult {
+	// Each group holds the indexes of the ops on one book, or one create
+	var groups [][]int
+	byBook := make(map[bson.ObjectID]int)
+	for i, op := range ops {
+		if op.Kind == BulkCreate {
+			groups = append(groups, []int{i})
+			continue
+		}
+		g, ok := byBook[op.ID]
+		if !ok {
+			g = len(groups)
+			byBook[op.ID] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], i)
+	}
+
+	ts := now()
+	results := make([]BulkResult, len(ops))
+	next := make(chan []int)
+	var wg sync.WaitGroup
+	for range min(bulkWorkers, len(groups)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for group := range next {
+				for _, i := range group {
+					results[i] = s.bulkOp(ctx, ops[i], ts)
+				}
+			}
+		}()
+	}
+	for _, group := range groups {
+		next <- group
+	}
+	close(next)
+	wg.Wait()
+	return results
+}
+
+// bulkOp applies one op of a non-atomic BulkWrite, written at ts.
+func (s *MongoStore) bulkOp(ctx context.Context, op BulkOp, ts time.Time) BulkResult {
+	switch op.Kind {
+	case BulkCreate:
+		book := op.Book
+		book.ID = bson.NewObjectID()
+		book = created(book, ts)
+		if _, err := s.collection.InsertOne(ctx, book); err != nil {
+			return BulkResult{Err: mongoError(err)}
+		}
+		return BulkResult{Book: book}
+	case BulkUpdate:
+		before, err := s.modify(ctx, op.ID, op.IfVersion, updateDoc(op.Book, ts))
+		if err != nil {
+			return BulkResult{Err: err}
+		}
+		return BulkResult{Before: before, Book: updated(before, op.Book, ts)}
+	case BulkDelete:
+		before, err := s.modify(ctx, op.ID, op.IfVersion, trashDoc(ts))
+		if err != nil {
+			return BulkResult{Err: err}
+		}
+		return BulkResult{Before: before, Book: trashed(before, ts)}
+	}
+	return BulkResult{Err: fmt.Errorf("unknown bulk operation %q", op.Kind)}
+}
+
+// modify applies update to the live book with the given ID if it is at
+// version ifVersion, and returns the book as it was.
+func (s *MongoStore) modify(ctx context.Context, id bson.ObjectID, ifVersion int64, update bson.M) (models.Book, error) {
+	var before models.Book
+	err := s.collection.FindOneAndUpdate(ctx, versionFilter(id, ifVersion), update,
+		options.FindOneAndUpdate().SetReturnDocument(options.Before)).Decode(&before)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.Book{}, s.missOrMismatch(ctx, id)
+	}
+	if err != nil {
+		return models.Book{}, mongoError(err)
+	}
+	return before, nil
+}
+
+// updateDoc is the update that writes book's fields at ts, leaving the
+// book as updated returns it.
+func updateDoc(book models.Book, ts time.Time) bson.M {
+	return bson.M{
+		"$set": bson.M{"title": book.Title, "author": book.Author, "year": book.Year, "updatedAt": ts},
+		"$inc": bson.M{"version": 1},
+	}
+}
+
+// trashDoc is the update that moves a book to the trash at ts, leaving it
+// as trashed returns it.
+func trashDoc(ts time.Time) bson.M {
+	return bson.M{
+		"$set": bson.M{"updatedAt": ts, "deletedAt": ts},
+		"$inc": bson.M{"version": 1},
+	}
+}
+
+// bulkWriteOrdered sends the ops of an atomic BulkWrite as one ordered
+// BulkWrite. The books they update or delete are read first, so that each
+// write is conditioned on the version it was checked against and its
+// result is known without reading the book back. It must run in a
+// transaction: a write that finds its book changed since then fails the
+// transaction with a write conflict rather than matching nothing.
+func (s *MongoStore) bulkWriteOrdered(ctx context.Context, ops []BulkOp) ([]BulkResult, error) {
+	current, err := s.bulkTargets(ctx, ops)
+	if err != nil {
+		return nil, err
+	}
+
+	ts := now()
+	results := make([]BulkResult, len(ops))
+	var writes []mongo.WriteModel
+	// opIndex maps each write to its op
+	var opIndex []int
+	for i, op := range ops {
+		r := &results[i]
+		if op.Kind == BulkCreate {
+			book := op.Book
+			book.ID = bson.NewObjectID()
+			r.Book = created(book, ts)
+			writes = append(writes, mongo.NewInsertOneModel().SetDocument(r.Book))
+			opIndex = append(opIndex, i)
+			continue
+		}
+
+		existing, ok := current[op.ID]
+		switch {
+		case op.Kind != BulkUpdate && op.Kind != BulkDelete:
+			r.Err = fmt.Errorf("unknown bulk operation %q", op.Kind)
+			continue
+		case !ok:
+			r.Err = ErrNotFound
+			continue
+		case op.IfVersion != AnyVersion && existing.Version != op.IfVersion:
+			r.Err = ErrVersionMismatch
+			continue
+		}
+
+		r.Before = existing
+		var update bson.M
+		if op.Kind == BulkUpdate {
+			r.Book = updated(existing, op.Book, ts)
+			update = updateDoc(op.Book, ts)
+			current[op.ID] = r.Book
+		} else {
+			r.Book = trashed(existing, ts)
+			update = trashDoc(ts)
+			delete(current, op.ID)
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().SetFilter(versionFilter(op.ID, existing.Version)).SetUpdate(update))
+		opIndex = append(opIndex, i)
+	}
+
+	// The batch is all or nothing, so do not start it when an op has
+	// already failed
+	if len(writes) == 0 || slices.ContainsFunc(results, func(r BulkResult) bool { return r.Err != nil }) {
+		return results, nil
+	}
+
+	_, err = s.collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(true))
+	var bwe mongo.BulkWriteException
+	if errors.As(err, &bwe) && bwe.WriteConcernError == nil {
+		for _, we := range bwe.WriteErrors {
+			results[opIndex[we.Index]] = BulkResult{Err: mongoError(we.WriteError)}
+		}
+	} else if err != nil {
+		return nil, mongoError(err)
+	}
+	return results, nil
+}
+
+// bulkTargets reads the live books that ops update or delete.
+func (s *MongoStore) bulkTargets(ctx context.Context, ops []BulkOp) (map[bson.ObjectID]models.Book, error) {
+	ids := bson.A{}
+	for _, op := range ops {
+		if op.Kind != BulkCreate {
+			ids = append(ids, op.ID)
+		}
+	}
+
+	books := make(map[bson.ObjectID]models.Book)
+	if len(ids) == 0 {
+		return books, nil
+	}
+
+	cursor, err := s.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}, "deletedAt": nil})
+	if err != nil {
+		return nil, mongoError(err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var book models.Book
+		if err := cursor.Decode(&book); err != nil {
+			return nil, mongoError(err)
+		}
+		books[book.ID] = book
+	}
+	return books, mongoError(cursor.Err())
+}
+
+func (s *MongoStore) AddHistory(ctx context.Context, entries ...models.HistoryEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	docs := make([]models.HistoryEntry, len(entries))
+	for i, entry := range entries {
+		entry.ID = bson.ObjectID{}
+		docs[i] = entry
+	}
+	_, err := s.history.InsertMany(ctx, docs)
+	return mongoError(err)
+}
+
+func (s *MongoStore) History(ctx context.Context, bookID bson.ObjectID) ([]models.HistoryEntry, error) {
+	findOpts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := s.history.Find(ctx, bson.M{"bookId": bookID}, findOpts)
+	if err != nil {
+		return nil, mongoError(err)
+	}
+	defer cursor.Close(ctx)
+
+	entries := []models.HistoryEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, mongoError(err)
+	}
+	return entries, nil
+}
+
+// reserveAttempts bounds the retries of ReserveIdempotencyKey when the key
+// it found is released or expires before it can be read or replaced.
+const reserveAttempts = 3
+
+// ReserveIdempotencyKey inserts the key, relying on the unique _id to find
+// out whether it is taken. The TTL index removes expired keys only about
+// once a minute, so one found expired is replaced here.
+func (s *MongoStore) ReserveIdempotencyKey(ctx context.Context, key, fingerprint string, expiresAt time.Time) (IdempotencyRecord, bool, error) {
+	rec := IdempotencyRecord{Key: key, Fingerprint: fingerprint, ExpiresAt: expiresAt}
+	for range reserveAttempts {
+		_, err := s.idempotency.InsertOne(ctx, rec)
+		if err == nil {
+			return rec, true, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return IdempotencyRecord{}, false, mongoError(err)
+		}
+
+		var existing IdempotencyRecord
+		err = s.idempotency.FindOne(ctx, bson.M{"_id": key}).Decode(&existing)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			continue
+		}
+		if err != nil {
+			return IdempotencyRecord{}, false, mongoError(err)
+		}
+		if existing.ExpiresAt.After(now()) {
+			return existing, false, nil
+		}
+
+		_, err = s.idempotency.DeleteOne(ctx, bson.M{"_id": key, "expiresAt": existing.ExpiresAt})
+		if err != nil {
+			return IdempotencyRecord{}, false, mongoError(err)
+		}
+	}
+	return IdempotencyRecord{}, false, fmt.Errorf("%w: idempotency key %q keeps changing", ErrConflict, key)
+}
+
+func (s *MongoStore) CompleteIdempotencyKey(ctx context.Context, key string, response StoredResponse) error {
+	res, err := s.idempotency.UpdateOne(ctx, bson.M{"_id": key}, bson.M{"$set": bson.M{"response": response}})
+	if err != nil {
+		return mongoError(err)
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *MongoStore) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	_, err := s.idempotency.DeleteOne(ctx, bson.M{"_id": key, "response": nil})
+	return mongoError(err)
+}
+
+// codeIllegalOperation is the server error for operations the deployment
+// does not allow, such as transactions on a standalone server.
+const codeIllegalOperation = 20
+
+// mongoError translates a driver error into the store errors that callers
+// can act on, keeping the driver error in the chain for logging. Server
+// selection failures are checked before timeouts because they also end in
+// a deadline when no server is reachable.
+func mongoError(err error) error {
+	var se mongo.ServerError
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return ErrNotFound
+	case mongo.IsDuplicateKeyError(err):
+		return fmt.Errorf("%w: %w", ErrConflict, err)
+	case errors.As(err, &se) && se.HasErrorCode(codeIllegalOperation):
+		return fmt.Errorf("%w: %w", ErrUnsupported, err)
+	case errors.As(err, &topology.ServerSelectionError{}),
+		errors.Is(err, mongo.ErrClientDisconnected),
+		mongo.IsNetworkError(err):
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	case mongo.IsTimeout(err):
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	}
+	return err
+}
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/x/mongo/driver/topology"
+)
+
+func TestMongoError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"nil", nil, nil},
+		{"no documents", mongo.ErrNoDocuments, ErrNotFound},
+		{
+			"duplicate key",
+			mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000, Message: "E11000 duplicate key error"}}},
+			ErrConflict,
+		},
+		{
+			"illegal operation",
+			mongo.CommandError{Code: codeIllegalOperation, Message: "Transaction numbers are only allowed on a replica set member"},
+			ErrUnsupported,
+		},
+		{"server selection", topology.ServerSelectionError{Wrapped: errors.New("no reachable servers")}, ErrUnavailable},
+		{"wrapped server selection", fmt.Errorf("finding book: %w", topology.ServerSelectionError{}), ErrUnavailable},
+		{"client disconnected", mongo.ErrClientDisconnected, ErrUnavailable},
+		{"deadline", context.DeadlineExceeded, ErrTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mongoError(tt.err)
+			if tt.want == nil {
+				if got != nil {
+					t.Fatalf("mongoError(nil) = %v, want nil", got)
+				}
+				return
+			}
+			if !errors.Is(got, tt.want) {
+				t.Errorf("mongoError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMongoErrorKeepsUnknownErrors(t *testing.T) {
+	err := errors.New("something else")
+	if got := mongoError(err); got != err {
+		t.Errorf("mongoError(%v) = %v, want it unchanged", err, got)
+	}
+}
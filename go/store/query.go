@@ -0,0 +1,202 @@
+package store
+
+import (
+	"bytes"
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"go-crud/models"
+)
+
+// Filter operators understood by every BookStore.
+const (
+	OpEq  = "eq"
+	OpGt  = "gt"
+	OpGte = "gte"
+	OpLt  = "lt"
+	OpLte = "lte"
+)
+
+// Filter restricts List to books whose Field compares to Value with Op.
+// Field is the stored (bson) field name and Value is a string, an int or a
+// time.Time.
+type Filter struct {
+	Field string
+	Op    string
+	Value any
+}
+
+// SortField orders List results by a stored field.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// ListOptions selects, orders and pages the books returned by List.
+// A zero Limit returns every matching book.
+//
+// After switches to keyset paging: only books that sort strictly after the
+// given key, as returned by SortKey, are listed. It is used instead of
+// Offset.
+//
+// Trashed lists the books in the trash instead of the live ones.
+//
+// Fields, when set, names the stored fields to load; _id is always loaded
+// and the other fields are left zero.
+type ListOptions struct {
+	Filters []Filter
+	Sort    []SortField
+	Offset  int64
+	Limit   int64
+	After   []any
+	Trashed bool
+	Fields  []string
+}
+
+// ListResult is one page of books plus the number of books that matched
+// the filters before paging.
+type ListResult struct {
+	Books []models.Book
+	Total int64
+}
+
+// StableSort returns sortFields with a trailing _id field, unless one is
+// already present, so that every book has a unique position.
+func StableSort(sortFields []SortField) []SortField {
+	for _, f := range sortFields {
+		if f.Field == "_id" {
+			return sortFields
+		}
+	}
+	return append(slices.Clip(sortFields), SortField{Field: "_id"})
+}
+
+// project returns book with only _id and the given stored fields, or the
+// whole book when fields is nil.
+func project(book models.Book, fields []string) models.Book {
+	if fields == nil {
+		return book
+	}
+
+	out := models.Book{ID: book.ID}
+	for _, f := range fields {
+		switch f {
+		case "title":
+			out.Title = book.Title
+		case "author":
+			out.Author = book.Author
+		case "year":
+			out.Year = book.Year
+		case "version":
+			out.Version = book.Version
+		case "createdAt":
+			out.CreatedAt = book.CreatedAt
+		case "updatedAt":
+			out.UpdatedAt = book.UpdatedAt
+		case "deletedAt":
+			out.DeletedAt = book.DeletedAt
+		}
+	}
+	return out
+}
+
+// SortKey returns the values of book for each field of StableSort(sortFields).
+// It is the key to pass in ListOptions.After to resume listing after book.
+func SortKey(book models.Book, sortFields []SortField) []any {
+	stable := StableSort(sortFields)
+	key := make([]any, len(stable))
+	for i, f := range stable {
+		key[i] = fieldValue(book, f.Field)
+	}
+	return key
+}
+
+// fieldValue returns the value of a stored field of book, for the stores
+// that evaluate filters and sorts in Go.
+func fieldValue(book models.Book, field string) any {
+	switch field {
+	case "_id":
+		return book.ID
+	case "title":
+		return book.Title
+	case "author":
+		return book.Author
+	case "year":
+		return book.Year
+	case "createdAt":
+		return book.CreatedAt
+	case "updatedAt":
+		return book.UpdatedAt
+	case "deletedAt":
+		return book.DeletedAt
+	}
+	return nil
+}
+
+// compareValues orders two field values. It reports false when the values
+// are not of the same comparable kind.
+func compareValues(a, b any) (int, bool) {
+	switch av := a.(type) {
+	case int:
+		if bv, ok := b.(int); ok {
+			return cmp.Compare(av, bv), true
+		}
+	case string:
+		if bv, ok := b.(string); ok {
+			return strings.Compare(av, bv), true
+		}
+	case time.Time:
+		if bv, ok := b.(time.Time); ok {
+			return av.Compare(bv), true
+		}
+	case bson.ObjectID:
+		if bv, ok := b.(bson.ObjectID); ok {
+			return bytes.Compare(av[:], bv[:]), true
+		}
+	}
+	return 0, false
+}
+
+func (f Filter) matches(book models.Book) bool {
+	c, ok := compareValues(fieldValue(book, f.Field), f.Value)
+	if !ok {
+		return false
+	}
+	switch f.Op {
+	case OpEq:
+		return c == 0
+	case OpGt:
+		return c > 0
+	case OpGte:
+		return c >= 0
+	case OpLt:
+		return c < 0
+	case OpLte:
+		return c <= 0
+	}
+	return false
+}
+
+// compareKeys orders two sort keys taken with the same sort fields.
+func compareKeys(a, b []any, sortFields []SortField) int {
+	for i, f := range StableSort(sortFields) {
+		c, _ := compareValues(a[i], b[i])
+		if f.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareBooks orders two books by the given sort fields, falling back to
+// their IDs so that pages are stable.
+func compareBooks(a, b models.Book, sortFields []SortField) int {
+	return compareKeys(SortKey(a, sortFields), SortKey(b, sortFields), sortFields)
+}
@@ -0,0 +1,50 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff spaces out retries: the wait doubles from Initial after each
+// failure up to Max, and is jittered so that clients restarted together
+// do not retry in lockstep.
+type Backoff struct {
+	// Attempts is the most attempts made; zero retries until the context
+	// is done.
+	Attempts int
+	Initial  time.Duration
+	Max      time.Duration
+}
+
+// Retry calls fn until it succeeds, the attempts run out or ctx is done,
+// and then returns the last error. what names the operation in logs and
+// errors.
+func (b Backoff) Retry(ctx context.Context, what string, fn func(context.Context) error) error {
+	wait := b.Initial
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		if b.Attempts > 0 && attempt >= b.Attempts {
+			return fmt.Errorf("%s: giving up after %d attempts: %w", what, attempt, err)
+		}
+
+		// Keep half of the wait and randomize the rest
+		sleep := wait/2 + rand.N(wait/2+1)
+		slog.WarnContext(ctx, "retrying after failure",
+			"operation", what, "attempt", attempt, "wait", sleep.Round(time.Millisecond).String(), "error", err)
+
+		timer := time.NewTimer(sleep)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%s: %w", what, ctx.Err())
+		case <-timer.C:
+		}
+		wait = min(wait*2, b.Max)
+	}
+}
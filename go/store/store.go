@@ -0,0 +1,112 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"go-crud/models"
+)
+
+var (
+	// ErrNotFound is returned when no book matches the requested ID.
+	ErrNotFound = errors.New("book not found")
+	// ErrConflict is returned when a write would break a uniqueness rule,
+	// such as a unique index.
+	ErrConflict = errors.New("book conflicts with an existing book")
+	// ErrUnavailable is returned when the backing database cannot be
+	// reached. The operation may succeed if retried later.
+	ErrUnavailable = errors.New("book store unavailable")
+	// ErrTimeout is returned when an operation ran out of time.
+	ErrTimeout = errors.New("book store timed out")
+	// ErrVersionMismatch is returned when a conditional write finds the
+	// book at a different version than expected.
+	ErrVersionMismatch = errors.New("book version does not match")
+	// ErrUnsupported is returned when the store cannot perform an
+	// operation in its current setup, such as a transaction on a
+	// standalone MongoDB server.
+	ErrUnsupported = errors.New("operation not supported by the book store")
+)
+
+// AnyVersion makes Update and Delete apply whatever the book's version.
+const AnyVersion int64 = -1
+
+// now returns the time stores record for writes, at the millisecond
+// precision MongoDB keeps so that every store reports the same value.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+// BookStore is the persistence layer used by the book controllers.
+//
+// Deleted books are moved to the trash: they keep their ID but every
+// method except List with Trashed, Restore, Purge and PurgeTrashed treats
+// them as not found.
+type BookStore interface {
+	List(ctx context.Context, opts ListOptions) (ListResult, error)
+	Get(ctx context.Context, id bson.ObjectID) (models.Book, error)
+	// GetMany returns the books with the given IDs that exist, in no
+	// particular order. fields limits the fields loaded like
+	// ListOptions.Fields.
+	GetMany(ctx context.Context, ids []bson.ObjectID, fields []string) ([]models.Book, error)
+	Create(ctx context.Context, book models.Book) (models.Book, error)
+	// Update and Delete only apply when the stored book is at version
+	// ifVersion, or unconditionally for AnyVersion.
+	Update(ctx context.Context, id bson.ObjectID, book models.Book, ifVersion int64) (models.Book, error)
+	// Delete returns the book as it is in the trash.
+	Delete(ctx context.Context, id bson.ObjectID, ifVersion int64) (models.Book, error)
+	// Restore takes a book out of the trash.
+	Restore(ctx context.Context, id bson.ObjectID) (models.Book, error)
+	// Purge removes a book for good, whether or not it is in the trash,
+	// and returns it as it was.
+	Purge(ctx context.Context, id bson.ObjectID) (models.Book, error)
+	// PurgeTrashed removes the books moved to the trash before cutoff and
+	// returns how many there were.
+	PurgeTrashed(ctx context.Context, cutoff time.Time) (int64, error)
+	// Search returns up to limit books matching whole words of query over
+	// title and author, best match first, and how many books matched.
+	Search(ctx context.Context, query string, limit int64) (SearchResult, error)
+	// BulkWrite applies ops and returns one result per op. Ops on the same
+	// book are applied in the order given. A failed op does not stop the
+	// others unless atomic is set, in which case either every op is
+	// applied or none is. The error is only for failures of the whole
+	// batch.
+	BulkWrite(ctx context.Context, ops []BulkOp, atomic bool) ([]BulkResult, error)
+
+	// AddHistory appends entries to the history of their books, assigning
+	// them IDs.
+	AddHistory(ctx context.Context, entries ...models.HistoryEntry) error
+	// History returns the entries recorded for a book, oldest first. It
+	// keeps them after the book is purged.
+	History(ctx context.Context, bookID bson.ObjectID) ([]models.HistoryEntry, error)
+
+	// ReserveIdempotencyKey claims key for a request with the given
+	// fingerprint until expiresAt. When the key is already claimed it
+	// returns the existing record and false instead.
+	ReserveIdempotencyKey(ctx context.Context, key, fingerprint string, expiresAt time.Time) (IdempotencyRecord, bool, error)
+	// CompleteIdempotencyKey stores the response of the request that
+	// claimed key.
+	CompleteIdempotencyKey(ctx context.Context, key string, response StoredResponse) error
+	// ReleaseIdempotencyKey gives up the claim on a key whose request did
+	// not complete, so that it can be retried.
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+
+	// Ping checks that the store can be reached.
+	Ping(ctx context.Context) error
+}
+
+// SearchResult is the best matches found by Search plus the number of
+// books that matched before the limit.
+type SearchResult struct {
+	Hits  []SearchHit
+	Total int64
+}
+
+// SearchHit is a book found by Search with its relevance score. Scores are
+// only comparable within one result set.
+type SearchHit struct {
+	Book  models.Book
+	Score float64
+}
@@ -0,0 +1,30 @@
+package store
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// PurgeExpired removes the books that have been in the trash for longer
+// than retention, checking every interval until ctx is done. Failures are
+// logged and retried at the next check.
+func PurgeExpired(ctx context.Context, s BookStore, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := s.PurgeTrashed(ctx, now().Add(-retention))
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to purge trashed books", "error", err)
+		} else if purged > 0 {
+			slog.InfoContext(ctx, "purged trashed books", "count", purged, "retention", retention.String())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}